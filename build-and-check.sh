@@ -29,61 +29,6 @@ echo "BUILD_IMAGE: $BUILD_IMAGE"
 echo "RUNTIME_IMAGE: $RUNTIME_IMAGE"
 echo ""
 
-# Check if runtime image is distroless
-echo "==================================================================="
-echo "Checking if runtime image has OpenSSL binary"
-echo "==================================================================="
-
-# Pull the runtime image to inspect it
-docker pull "$RUNTIME_IMAGE" >/dev/null 2>&1 || true
-
-# Check if the image has OpenSSL binary by trying to execute it
-HAS_OPENSSL=false
-
-# Try common OpenSSL binary locations
-for openssl_path in /usr/bin/openssl /bin/openssl /usr/local/bin/openssl openssl; do
-    if docker run --rm --entrypoint sh "$RUNTIME_IMAGE" -c "command -v $openssl_path" >/dev/null 2>&1; then
-        HAS_OPENSSL=true
-        echo "✓ Found OpenSSL binary at: $openssl_path"
-        break
-    fi
-done
-
-# If no shell, try direct execution
-if [ "$HAS_OPENSSL" = false ]; then
-    if docker run --rm --entrypoint /usr/bin/openssl "$RUNTIME_IMAGE" version >/dev/null 2>&1; then
-        HAS_OPENSSL=true
-        echo "✓ Found OpenSSL binary at: /usr/bin/openssl"
-    elif docker run --rm --entrypoint /bin/openssl "$RUNTIME_IMAGE" version >/dev/null 2>&1; then
-        HAS_OPENSSL=true
-        echo "✓ Found OpenSSL binary at: /bin/openssl"
-    fi
-fi
-
-echo ""
-
-if [ "$HAS_OPENSSL" = false ]; then
-    echo "==================================================================="
-    echo "FIPS Compliance Check Result: NON-COMPLIANT"
-    echo "==================================================================="
-    echo "Reason: Runtime image does not contain OpenSSL binary"
-    echo ""
-    echo "Images without OpenSSL cannot support FIPS compliance because:"
-    echo "  - FIPS mode requires OpenSSL with FIPS module"
-    echo "  - Go systemcrypto depends on OpenSSL for cryptographic operations"
-    echo "  - No OpenSSL means no FIPS cryptographic provider available"
-    echo ""
-    echo "This is common in:"
-    echo "  - Distroless images"
-    echo "  - Minimal/scratch-based images"
-    echo "  - Images using alternative crypto libraries"
-    echo "==================================================================="
-    exit 1
-fi
-
-echo "✓ Runtime image has OpenSSL binary, proceeding with FIPS check"
-echo ""
-
 # Phase 2: Build the Docker image
 IMAGE_TAG="fips-checker:${RUNTIME_IMAGE//\//-}"
 IMAGE_TAG="${IMAGE_TAG//:/-}"
@@ -100,11 +45,43 @@ docker build \
     -t "$IMAGE_TAG" \
     .
 
+echo ""
+
+# Phase 3: Inventory the crypto runtime of the image
+echo "==================================================================="
+echo "Phase 3: Checking the crypto runtime of the image"
+echo "==================================================================="
+echo ""
+
+# The inventory stage runs the checker from the build image against a copy of
+# the runtime image's filesystem. It lists every libcrypto, libssl, FIPS provider
+# and fipsmodule.cnf, and fails if there is no libcrypto at all.
+docker build \
+    --build-arg BUILD_IMAGE="$BUILD_IMAGE" \
+    --build-arg RUNTIME_IMAGE="$RUNTIME_IMAGE" \
+    --target inventory \
+    -t "$IMAGE_TAG-inventory" \
+    . >/dev/null
+
+if ! docker run --rm "$IMAGE_TAG-inventory"; then
+    echo ""
+    echo "==================================================================="
+    echo "FIPS Compliance Check Result: NON-COMPLIANT"
+    echo "==================================================================="
+    echo "Reason: Runtime image does not contain libcrypto"
+    echo ""
+    echo "Go systemcrypto binaries load OpenSSL at runtime, so an image without"
+    echo "libcrypto and a FIPS provider cannot run them in FIPS mode. This is"
+    echo "common in distroless, scratch-based and minimal images."
+    echo "==================================================================="
+    exit 1
+fi
+
 echo ""
 echo "==================================================================="
-echo "Phase 3: Running the built image"
+echo "Phase 4: Running the built image"
 echo "==================================================================="
 echo ""
 
-# Phase 3: Run the built image
+# Phase 4: Run the built image
 docker run --rm "$IMAGE_TAG"
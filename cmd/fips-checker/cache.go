@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/binarychecker"
+	"github.com/bahe-msft/fips-check/internal/cache"
+	"github.com/bahe-msft/fips-check/internal/osfips"
+)
+
+// cacheFlag registers the -cache-dir flag.
+func cacheFlag(flags *flag.FlagSet) *string {
+	return flags.String("cache-dir", "", "directory to cache analysis results in across scans (default: no cache)")
+}
+
+// withCache configures opts to use the cache in dir, if set. Runtime probe
+// results are keyed by the host's OpenSSL, so the host is probed if needed.
+func withCache(ctx context.Context, opts binarychecker.Options, dir string, host *hostOptions) binarychecker.Options {
+	if dir == "" {
+		return opts
+	}
+	opts.Cache = cache.New(dir)
+	if !opts.DisableRuntimeProbe {
+		opts.HostFingerprint = host.fingerprint(ctx)
+	}
+	return opts
+}
+
+// fingerprint identifies the host's crypto runtime, which runtime probes depend on.
+func (h *hostOptions) fingerprint(ctx context.Context) string {
+	parts := append(h.opensslFingerprint(ctx), strconv.FormatBool(osfips.Detect(h.root).Enabled()))
+	return cache.Fingerprint(parts...)
+}
+
+// runCache inspects and maintains the analysis cache.
+func runCache(args []string) error {
+	flags := flag.NewFlagSet("cache", flag.ContinueOnError)
+	dir := cacheFlag(flags)
+	maxAge := flags.Duration("max-age", 30*24*time.Hour, "prune entries not used for this long")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() == 0 {
+		return errors.New("cache: expected one of stats, clear or prune")
+	}
+	// Flags may also follow the action, as in "prune -max-age 24h"
+	action := flags.Arg(0)
+	if err := flags.Parse(flags.Args()[1:]); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return fmt.Errorf("cache: unexpected arguments %q", flags.Args())
+	}
+	if *dir == "" {
+		return errors.New("cache: -cache-dir is required")
+	}
+	c := cache.New(*dir)
+
+	switch action {
+	case "stats":
+		stats, err := c.Stats()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Cache: %s\n", c.Dir())
+		fmt.Printf("Checker Version: %s\n", cache.CheckerVersion())
+		fmt.Printf("Entries: %d (%d bytes)\n", stats.Entries, stats.Bytes)
+	case "clear":
+		removed, err := c.Clear()
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d entries from %s\n", removed, c.Dir())
+	case "prune":
+		removed, err := c.Prune(*maxAge)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Removed %d entries unused for %s or from other checker versions from %s\n", removed, *maxAge, c.Dir())
+	default:
+		return fmt.Errorf("cache: unknown action %q, expected one of stats, clear or prune", action)
+	}
+	return nil
+}
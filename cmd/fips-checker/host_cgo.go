@@ -0,0 +1,103 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/opensslsetup"
+)
+
+// hostCheckSupported reports whether this build can load the host's OpenSSL.
+// It is false in builds with CGO_ENABLED=0.
+const hostCheckSupported = true
+
+// hostProber loads the host's OpenSSL for the host check and self-tests.
+type hostProber struct {
+	opensslsetup.Prober
+}
+
+// checkOpenSSL loads OpenSSL through the prober, prints what was loaded and
+// reports whether it is FIPS capable.
+func checkOpenSSL(ctx context.Context, host *hostOptions) bool {
+	prober := &host.prober
+	err := prober.Init(ctx)
+	result := prober.Result()
+
+	for _, c := range result.Candidates {
+		status := "not found"
+		if c.Exists {
+			status = fmt.Sprintf("found, FIPS: %t", c.FIPS)
+		}
+		fmt.Printf("Candidate %s: %s\n", c.Name, status)
+	}
+	fmt.Printf("Library: %s\n", result.Library)
+
+	if err != nil {
+		fmt.Printf("⚠️  Status: OpenSSL could not be loaded: %v\n", err)
+		return false
+	}
+
+	fmt.Printf("OpenSSL Version: %s\n", result.OpenSSLVersion)
+	fmt.Printf("FIPS Capable: %t\n", result.FIPSCapable)
+	printProviders(result.Providers)
+	for _, p := range result.Providers.Providers {
+		if module, ok := cmvp.ProviderModule(p.Name); ok {
+			printModule(cmvp.Detected{Module: module, Version: p.Version, Build: p.BuildInfo}, host.catalog)
+		}
+	}
+
+	if result.FIPSCapable {
+		fmt.Printf("✅ Status: Host is FIPS capable\n")
+	} else {
+		fmt.Printf("⚠️  Status: Host is NOT FIPS capable\n")
+	}
+	return result.FIPSCapable
+}
+
+// printProviders prints the OpenSSL 3 provider configuration
+func printProviders(details opensslsetup.ProviderDetails) {
+	if !details.Supported {
+		return
+	}
+	fmt.Printf("Providers:\n")
+	for _, p := range details.Providers {
+		fmt.Printf("    %s %s (active: %t)\n", p.Name, p.Version, p.Active)
+		if p.BuildInfo != "" {
+			fmt.Printf("        Build Info: %s\n", p.BuildInfo)
+		}
+	}
+	fmt.Printf("FIPS Provider Active: %t\n", details.FIPSProviderActive)
+	fmt.Printf("Default Properties FIPS: %t\n", details.DefaultPropertiesFIPS)
+	if details.DefaultProperties != "" {
+		fmt.Printf("Default Properties: %s\n", details.DefaultProperties)
+	}
+	fmt.Printf("OpenSSL Directory: %s\n", details.ConfigDir)
+	fmt.Printf("Config File: %s\n", details.ConfigFile)
+	if details.FIPSModuleConfig != "" {
+		fmt.Printf("FIPS Module Config: %s\n", details.FIPSModuleConfig)
+	}
+	fmt.Printf("Modules Directory: %s\n", details.ModulesDir)
+}
+
+// opensslFingerprint returns the parts of the host fingerprint that identify
+// the host's OpenSSL.
+func (h *hostOptions) opensslFingerprint(ctx context.Context) []string {
+	err := h.prober.Init(ctx)
+	result := h.prober.Result()
+	parts := []string{
+		result.Library,
+		result.OpenSSLVersion,
+		strconv.FormatBool(result.FIPSCapable),
+	}
+	for _, p := range result.Providers.Providers {
+		parts = append(parts, p.Name+" "+p.Version)
+	}
+	if err != nil {
+		parts = append(parts, err.Error())
+	}
+	return parts
+}
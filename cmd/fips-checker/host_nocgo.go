@@ -0,0 +1,35 @@
+//go:build !cgo
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// hostCheckSupported reports whether this build can load the host's OpenSSL.
+// It is false in builds with CGO_ENABLED=0.
+const hostCheckSupported = false
+
+// hostProber holds the -libcrypto flag, which has no effect without cgo.
+type hostProber struct {
+	Library string
+}
+
+// checkOpenSSL reports that OpenSSL cannot be loaded, and the host therefore
+// not FIPS capable.
+func checkOpenSSL(context.Context, *hostOptions) bool {
+	fmt.Printf("⚠️  Status: OpenSSL could not be loaded: %v\n", errNoCgo)
+	return false
+}
+
+// opensslFingerprint returns the parts of the host fingerprint that identify
+// the host's OpenSSL, which this build cannot load.
+func (h *hostOptions) opensslFingerprint(context.Context) []string {
+	return []string{errNoCgo.Error()}
+}
+
+// runSelfTest reports that the self-tests need the host's OpenSSL.
+func runSelfTest(context.Context, []string) error {
+	return fmt.Errorf("selftest: %w", errNoCgo)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+
+	"github.com/bahe-msft/fips-check/internal/binarychecker"
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+	"github.com/bahe-msft/fips-check/internal/ociimage"
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+	"github.com/bahe-msft/fips-check/internal/verdict"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// runImage scans the platforms of an OCI image layout or image archive.
+// By default every platform of a manifest list is scanned in one run.
+func runImage(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("image", flag.ContinueOnError)
+	platform := flags.String("platform", "all", `platform to scan as os/arch[/variant], or "all" for every platform in the image`)
+	host := hostFlags(flags)
+	showProgress := progressFlag(flags)
+	cacheDir := cacheFlag(flags)
+	symlinks := symlinkFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("image: expected exactly one image layout directory or archive")
+	}
+	if err := host.loadCatalog(); err != nil {
+		return err
+	}
+
+	layout, err := ociimage.Open(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer layout.Close()
+
+	images, err := layout.Images()
+	if err != nil {
+		return err
+	}
+	if *platform != "all" {
+		requested, err := ociimage.ParsePlatform(*platform)
+		if err != nil {
+			return err
+		}
+		var matching []ociimage.Image
+		for _, img := range images {
+			if img.Platform.Matches(requested) {
+				matching = append(matching, img)
+			}
+		}
+		if len(matching) == 0 {
+			return fmt.Errorf("image has no manifest for platform %s", requested)
+		}
+		images = matching
+	}
+
+	// The host check is informational: each platform is judged by its own crypto runtime
+	checkHost(ctx, host)
+	// Binaries of foreign platforms are reported with ErrForeignArch and judged statically
+	opts := withCache(ctx, binarychecker.Options{Symlinks: *symlinks}, *cacheDir, host)
+
+	compliant := true
+	for _, img := range images {
+		fmt.Printf("\n=== Image Platform: %s ===\n", img.Platform)
+		fmt.Printf("Manifest: %s\n", img.Digest)
+		if !nativePlatform(img.Platform) {
+			fmt.Printf("Note: foreign platform, binaries are checked statically only\n")
+		}
+
+		scan, err := scanImage(ctx, img, newProgressBar(*showProgress), opts)
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %w", img.Platform, err)
+		}
+		printInventory(scan.inventory)
+		if len(scan.modules) == 0 {
+			fmt.Printf("FIPS Module: none found in image\n")
+		}
+		for _, m := range scan.modules {
+			printModule(m, host.catalog)
+		}
+		result := verdict.Evaluate(scan.inventory, scan.modules, scan.reports, verdict.Options{Entrypoint: scan.entrypoint})
+		status := verdictStatus(result, "image")
+		printReports(scan.reports, status)
+		printEntrypoint(scan, result, status)
+		printVerdict(result)
+		compliant = compliant && result.Compliant
+	}
+	if !compliant {
+		return errImageNotCompliant
+	}
+	return nil
+}
+
+// nativePlatform reports whether the binaries of an image for platform run on
+// this host, so that they can be probed at runtime.
+func nativePlatform(platform ociimage.Platform) bool {
+	return platform.OS == runtime.GOOS && binarychecker.NativeArchitecture(platform.Architecture)
+}
+
+// errImageNotCompliant is returned when any scanned platform fails its verdict.
+var errImageNotCompliant = errors.New("image is not FIPS compliant")
+
+// imageScan is the result of scanning one platform of an image.
+type imageScan struct {
+	reports   []report.BinaryReport
+	modules   []cmvp.Detected
+	inventory cryptoinventory.Inventory
+	// entrypoint is the path of the executable the image runs by default
+	entrypoint    string
+	entrypointErr error
+	// entrypointProbe describes how the entrypoint was probed, or why it was not
+	entrypointProbe string
+}
+
+// scanImage unpacks the image into a temporary root filesystem and scans it
+// for Go binaries, the crypto runtime and validated module candidates.
+// Go binaries are checked layer by layer, so that layers found in the cache
+// are not checked again.
+func scanImage(ctx context.Context, img ociimage.Image, bar *progressBar, opts binarychecker.Options) (imageScan, error) {
+	var scan imageScan
+	dir, err := os.MkdirTemp("", "fips-check-rootfs-")
+	if err != nil {
+		return scan, err
+	}
+	defer os.RemoveAll(dir)
+
+	opts.Progress = bar.update
+	layers := binarychecker.NewLayeredScan(dir, opts)
+	err = img.UnpackLayers(ctx, dir, func(layer ociimage.Descriptor, changes ociimage.LayerChanges) error {
+		return layers.AddLayer(ctx, layer.Digest, changes)
+	})
+	bar.clear()
+	if err != nil {
+		return scan, err
+	}
+	scan.reports = layers.Reports()
+	if err := checkEntrypoint(ctx, dir, img, &scan, opts); err != nil {
+		return scan, err
+	}
+	if scan.inventory, err = cryptoinventory.Scan(ctx, dir); err != nil {
+		return scan, err
+	}
+	// Report paths as seen inside the image rather than the temporary directory
+	scan.inventory.Root = "/"
+	scan.modules, err = cmvp.FindModules(dir)
+	return scan, err
+}
+
+// checkEntrypoint resolves the executable the image runs by default and, if
+// it is a Go binary, flags its report and moves it first. Unlike the other
+// binaries, whose results are shared across images by the layer cache, it is
+// probed again inside the root filesystem with the image environment, which
+// its runtime check may depend on. Entrypoints of a foreign platform cannot be
+// executed and keep their static verdict.
+func checkEntrypoint(ctx context.Context, dir string, img ociimage.Image, scan *imageScan, opts binarychecker.Options) error {
+	entrypoint, err := img.Config.Entrypoint(dir)
+	if errors.Is(err, ociimage.ErrNoCommand) {
+		return nil
+	}
+	if err != nil {
+		scan.entrypointErr = err
+		return nil
+	}
+	scan.entrypoint = entrypoint
+
+	i := slices.IndexFunc(scan.reports, func(r report.BinaryReport) bool { return r.RelativePath == entrypoint })
+	if i < 0 {
+		// With symlinks skipped, a symlinked entrypoint is only reported at its target
+		target, err := rootfs.Rel(dir, entrypoint)
+		if err != nil {
+			return err
+		}
+		i = slices.IndexFunc(scan.reports, func(r report.BinaryReport) bool { return r.RelativePath == target })
+	}
+	if i < 0 {
+		return nil
+	}
+	entry := scan.reports[i]
+	entry.Entrypoint = true
+
+	if nativePlatform(img.Platform) && !entry.GoBinaryDetails.ForeignArchitecture {
+		path, err := rootfs.Join(dir, entrypoint)
+		if err != nil {
+			return err
+		}
+		// A non-nil environment replaces the checker's own, even if the image sets none
+		opts.Env = append([]string{}, img.Config.Config.Env...)
+		opts.Progress = nil
+		result, err := binarychecker.Scan(ctx, path, opts)
+		if err != nil {
+			return err
+		}
+		if len(result.Binaries) == 1 {
+			checked := result.Binaries[0]
+			entry.GoBinaryDetails, entry.Cached, entry.Error = checked.GoBinaryDetails, checked.Cached, checked.Error
+			scan.entrypointProbe = fmt.Sprintf("%s, with the image environment", entry.GoBinaryDetails.RuntimeOutcome)
+			if entry.Error != nil {
+				scan.entrypointProbe += fmt.Sprintf(" (%v)", entry.Error)
+			}
+		}
+	} else {
+		entry.GoBinaryDetails.StaticOnly = true
+		entry.GoBinaryDetails.RuntimeOutcome = report.RuntimeOutcomeSkipped
+		scan.entrypointProbe = fmt.Sprintf("not run, the %s platform cannot be executed on this host (static analysis only)", img.Platform)
+	}
+	scan.reports = slices.Insert(slices.Delete(scan.reports, i, i+1), 0, entry)
+	return nil
+}
+
+// printEntrypoint prints the verdict for the executable the image runs by
+// default, whose report checkEntrypoint moved first, with the FIPS status
+// given by status.
+func printEntrypoint(scan imageScan, result verdict.Report, status binaryStatus) {
+	if scan.entrypoint == "" && scan.entrypointErr == nil {
+		return
+	}
+	fmt.Printf("\n=== Entrypoint Verdict ===\n")
+	switch {
+	case scan.entrypointErr != nil:
+		fmt.Printf("⚠️  Entrypoint could not be resolved: %v\n", scan.entrypointErr)
+	case result.Entrypoint == nil:
+		fmt.Printf("Entrypoint: /%s\n", scan.entrypoint)
+		fmt.Printf("    Not a Go binary\n")
+	default:
+		fmt.Printf("Entrypoint: /%s\n", scan.entrypoint)
+		fmt.Printf("    %s\n", status(0, scan.reports[0]))
+		if scan.entrypointProbe != "" {
+			fmt.Printf("    Runtime Probe: %s\n", scan.entrypointProbe)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+)
+
+// errNoLibcrypto is returned by the inventory command when the root has no
+// libcrypto, so that scripts can fail early.
+var errNoLibcrypto = errors.New("no libcrypto found")
+
+// runInventory lists the OpenSSL runtime of a filesystem tree, "/" by default.
+func runInventory(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	root := "/"
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+
+	inv, err := cryptoinventory.Scan(ctx, root)
+	if err != nil {
+		return err
+	}
+	printInventory(inv)
+	if !inv.HasLibcrypto() {
+		return errNoLibcrypto
+	}
+	return nil
+}
+
+// printInventory prints the crypto runtime section of a report.
+func printInventory(inv cryptoinventory.Inventory) {
+	fmt.Printf("\n=== Crypto Runtime ===\n")
+	fmt.Printf("Root: %s\n", inv.Root)
+
+	for _, item := range inv.Items {
+		switch {
+		case item.LinkTarget != "":
+			fmt.Printf("%-18s %s -> %s\n", item.Kind, item.Path, item.LinkTarget)
+		case item.Err != nil:
+			fmt.Printf("%-18s %s (error: %v)\n", item.Kind, item.Path, item.Err)
+		case item.Kind == cryptoinventory.KindFIPSModuleConfig:
+			fmt.Printf("%-18s %s (activates provider: %t)\n", item.Kind, item.Path, item.Activated)
+		case item.Kind == cryptoinventory.KindOpenSSLConfig:
+			fmt.Printf("%-18s %s\n", item.Kind, item.Path)
+			fmt.Printf("%-18s     Activates: %s\n", "", listOrNone(item.Providers))
+			if item.DefaultProperties != "" {
+				fmt.Printf("%-18s     Default Properties: %s\n", "", item.DefaultProperties)
+			}
+		default:
+			fmt.Printf("%-18s %s\n", item.Kind, item.Path)
+			if item.Module != "" {
+				fmt.Printf("%-18s     Module: %s\n", "", item.Module)
+			}
+			if item.Version != "" {
+				fmt.Printf("%-18s     Version: %s\n", "", item.Version)
+			}
+			if item.Soname != "" {
+				fmt.Printf("%-18s     Soname: %s\n", "", item.Soname)
+			}
+		}
+	}
+	if d := inv.Diagnostics; !d.Complete() {
+		fmt.Printf("⚠️  %d paths could not be read; runtime files below them are missing from the list\n", d.Count)
+		for _, e := range d.Errors {
+			fmt.Printf("    - %s: %v\n", e.Path, e.Err)
+		}
+		if omitted := d.Count - len(d.Errors); omitted > 0 {
+			fmt.Printf("    ... and %d more\n", omitted)
+		}
+	}
+
+	switch {
+	case !inv.HasLibcrypto():
+		fmt.Printf("❌ Status: No libcrypto found\n")
+		fmt.Printf("    Go systemcrypto binaries need libcrypto with a FIPS provider at runtime.\n")
+		fmt.Printf("    This is common in distroless, scratch-based and minimal images.\n")
+	case !inv.HasFIPSProvider():
+		fmt.Printf("⚠️  Status: libcrypto found, but no FIPS provider (ossl-modules/fips.so or symcryptprovider.so)\n")
+		fmt.Printf("    FIPS mode needs a FIPS-capable provider, such as the OpenSSL FIPS provider or SymCrypt-OpenSSL.\n")
+	case !inv.HasModule(cmvp.SymCryptOpenSSL) && !inv.HasFIPSModuleConfig():
+		// Only the OpenSSL FIPS provider is configured through fipsmodule.cnf
+		fmt.Printf("⚠️  Status: OpenSSL FIPS provider found, but no fipsmodule.cnf\n")
+	default:
+		fmt.Printf("✅ Status: libcrypto and a FIPS provider are present\n")
+	}
+}
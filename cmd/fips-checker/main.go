@@ -1,36 +1,292 @@
-//go:build cgo
-
 package main
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
 	"os"
 	"os/signal"
 	"strings"
 
-	"github.com/golang-fips/openssl/v2"
-
 	"github.com/bahe-msft/fips-check/internal/binarychecker"
-	_ "github.com/bahe-msft/fips-check/internal/opensslsetup"
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+	"github.com/bahe-msft/fips-check/internal/osfips"
+	"github.com/bahe-msft/fips-check/internal/verdict"
+	"github.com/bahe-msft/fips-check/report"
 )
 
-func main() {
-	checkHost()
+const usage = `Usage:
+  fips-checker                     Scan the root filesystem of this host or container
+  fips-checker scan [flags] [path] Scan the filesystem tree at path (default "/")
+  fips-checker image [flags] <layout>
+                                   Scan an OCI image layout directory or image archive
+  fips-checker selftest [flags]    Run cryptographic self-tests through the host's OpenSSL
+                                   (requires a build with cgo)
+  fips-checker inventory [path]    List every libcrypto, libssl, FIPS provider and
+                                   fipsmodule.cnf in the filesystem tree at path
+  fips-checker cache -cache-dir <dir> stats|clear|prune [-max-age 720h]
+                                   Show, clear or prune the analysis cache
+  fips-checker processes [-host-root /]
+                                   Check the Go processes running on this node
+                                   through /proc
+
+Common flags:
+  -libcrypto string                libcrypto to load for the host check
+                                   (default: search well-known versions)
+  -host-root string                root of the node filesystem to read the kernel
+                                   and OS FIPS mode from (default "/")
+  -cmvp-catalog string             CMVP catalog file whose entries take precedence
+                                   over the embedded catalog
+  -progress                        render scan progress on stderr
+                                   (default: on when stderr is a terminal)
+  -cache-dir string                directory to cache analysis results in across
+                                   scans and images (default: no cache)
+  -symlinks follow|skip            report symlinks to Go binaries as aliases of
+                                   their targets, resolved inside the scanned
+                                   tree, or skip them (default "follow")
 
+Scan flags:
+  -json                            print only the binary reports, as JSON
+  -strict                          fail if any path could not be scanned
+  -static                          check binaries statically only, without
+                                   runtime probes or the host OpenSSL check;
+                                   required in builds without cgo
+`
+
+func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt)
 	defer cancel()
 
-	reports, err := binarychecker.Check(ctx, "/")
+	command, args := "scan", os.Args[1:]
+	if len(args) > 0 {
+		command, args = args[0], args[1:]
+	}
+
+	var err error
+	switch command {
+	case "scan":
+		err = runScan(ctx, args)
+	case "image":
+		err = runImage(ctx, args)
+	case "selftest":
+		err = runSelfTest(ctx, args)
+	case "inventory":
+		err = runInventory(ctx, args)
+	case "cache":
+		err = runCache(args)
+	case "processes":
+		err = runProcesses(ctx, args)
+	case "help", "-h", "-help", "--help":
+		fmt.Print(usage)
+		return
+	default:
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", command, usage)
+		os.Exit(2)
+	}
+
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	printReports(reports)
+// runScan checks the host and scans a filesystem tree, "/" by default.
+func runScan(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("scan", flag.ContinueOnError)
+	host := hostFlags(flags)
+	showProgress := progressFlag(flags)
+	jsonOutput := flags.Bool("json", false, "print only the binary reports, as JSON")
+	strict := flags.Bool("strict", false, "fail if any path could not be scanned")
+	static := flags.Bool("static", false, "check binaries statically only, without runtime probes or the host OpenSSL check")
+	cacheDir := cacheFlag(flags)
+	symlinks := symlinkFlag(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	root := "/"
+	if flags.NArg() > 0 {
+		root = flags.Arg(0)
+	}
+	if !*static && !hostCheckSupported {
+		return fmt.Errorf("scan: %w; use -static for a static-only scan", errNoCgo)
+	}
+	opts := withCache(ctx, binarychecker.Options{Strict: *strict, Symlinks: *symlinks, DisableRuntimeProbe: *static}, *cacheDir, host)
+
+	if *jsonOutput {
+		scan, err := scanBinaries(ctx, root, *showProgress, opts)
+		if err != nil && !errors.Is(err, report.ErrIncompleteScan) {
+			return err
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if encodeErr := encoder.Encode(scan); encodeErr != nil {
+			return encodeErr
+		}
+		return err
+	}
+
+	if err := host.loadCatalog(); err != nil {
+		return err
+	}
+
+	hostFIPSCapable := false
+	if !*static {
+		hostFIPSCapable = checkHost(ctx, host)
+	}
+
+	inv, err := cryptoinventory.Scan(ctx, root)
+	if err != nil {
+		return err
+	}
+	printInventory(inv)
+	modules, err := cmvp.FindModules(root)
+	if err != nil {
+		return err
+	}
+
+	scan, err := scanBinaries(ctx, root, *showProgress, opts)
+	if err != nil && !errors.Is(err, report.ErrIncompleteScan) {
+		return err
+	}
+
+	// Runtime probes load the host's OpenSSL, which is the target's only when scanning the host root
+	useProbe := !opts.DisableRuntimeProbe && isHostRoot(root)
+	result := verdict.Evaluate(inv, modules, scan.Binaries, verdict.Options{UseRuntimeProbe: useProbe})
+	status := hostStatus(hostFIPSCapable)
+	if *static {
+		// Without the host check, binaries are judged by the scanned filesystem's crypto runtime
+		status = verdictStatus(result, "filesystem")
+	}
+	printReports(scan.Binaries, status)
+	printDiagnostics(scan.Diagnostics)
+	printVerdict(result)
+	return err
+}
+
+// isHostRoot reports whether root is the root directory of this machine,
+// however it is spelled, e.g. "/", "//" or a path that resolves to it.
+func isHostRoot(root string) bool {
+	info, err := os.Stat(root)
+	if err != nil {
+		return false
+	}
+	hostRoot, err := os.Stat("/")
+	return err == nil && os.SameFile(info, hostRoot)
+}
+
+// scanBinaries checks the Go binaries below root, rendering progress if requested.
+// With opts.Strict, the scan is returned along with report.ErrIncompleteScan.
+func scanBinaries(ctx context.Context, root string, showProgress bool, opts binarychecker.Options) (report.Scan, error) {
+	bar := newProgressBar(showProgress)
+	defer bar.clear()
+	opts.Progress = bar.update
+	return binarychecker.Scan(ctx, root, opts)
+}
+
+// errNoCgo is returned by the commands that load the host's OpenSSL, which
+// builds without cgo cannot do; see hostCheckSupported.
+var errNoCgo = errors.New("loading the host's OpenSSL requires a build with cgo (CGO_ENABLED=1)")
+
+// hostOptions configures the host check.
+type hostOptions struct {
+	prober hostProber
+	// root is where the kernel and OS FIPS signals are read from
+	root string
+	// catalogPath is a CMVP catalog file layered over the embedded catalog
+	catalogPath string
+	catalog     *cmvp.Catalog
+}
+
+// loadCatalog loads the CMVP catalog selected by -cmvp-catalog.
+func (h *hostOptions) loadCatalog() error {
+	if h.catalogPath == "" {
+		h.catalog = cmvp.Default()
+		return nil
+	}
+	catalog, err := cmvp.Load(h.catalogPath)
+	if err != nil {
+		return err
+	}
+	h.catalog = catalog
+	return nil
 }
 
-func printReports(reports []binarychecker.BinaryReport) {
+// hostFlags registers the -libcrypto, -host-root and -cmvp-catalog flags and
+// returns the options they configure.
+func hostFlags(flags *flag.FlagSet) *hostOptions {
+	host := &hostOptions{}
+	flags.StringVar(&host.prober.Library, "libcrypto", "", "libcrypto to load for the host check (default: search well-known versions)")
+	flags.StringVar(&host.root, "host-root", "/", "root of the node filesystem to read the kernel and OS FIPS mode from")
+	flags.StringVar(&host.catalogPath, "cmvp-catalog", "", "CMVP catalog file whose entries take precedence over the embedded catalog")
+	return host
+}
+
+// symlinkFlag registers the -symlinks flag, which selects the symlink policy.
+func symlinkFlag(flags *flag.FlagSet) *binarychecker.SymlinkPolicy {
+	policy := binarychecker.SymlinkFollow
+	flags.Func("symlinks", "symlink `policy`: \"follow\" to report symlinks to Go binaries as aliases of their targets, or \"skip\" (default \"follow\")", func(value string) error {
+		switch value {
+		case "follow":
+			policy = binarychecker.SymlinkFollow
+		case "skip":
+			policy = binarychecker.SymlinkSkip
+		default:
+			return fmt.Errorf("invalid symlink policy %q, expected follow or skip", value)
+		}
+		return nil
+	})
+	return &policy
+}
+
+// binaryStatus returns the FIPS status line printed for the i-th binary report.
+type binaryStatus func(i int, report report.BinaryReport) string
+
+// hostStatus judges binaries by their runtime check on the host, taking into
+// account whether the host crypto runtime is FIPS capable.
+func hostStatus(runtimeFIPSCapable bool) binaryStatus {
+	return func(_ int, report report.BinaryReport) string {
+		details := report.GoBinaryDetails
+		switch {
+		case !details.UseSystemcrypto:
+			return "❌ FIPS Status: NOT COMPLIANT (systemcrypto not in use)"
+		case details.FailsOnFIPSCheck:
+			return "❌ FIPS Status: NOT COMPLIANT (runtime check fails)"
+		case !runtimeFIPSCapable:
+			return "❌ FIPS Status: NOT COMPLIANT (host not FIPS capable)"
+		case details.StaticOnly:
+			return "✅ FIPS Status: COMPLIANT (static analysis only)"
+		default:
+			return "✅ FIPS Status: COMPLIANT"
+		}
+	}
+}
+
+// verdictStatus judges binaries as the verdict v of the root filesystem they
+// were found in does, whose Binaries are in the order of the reports, rather
+// than by runtime checks that do not use that filesystem's OpenSSL.
+func verdictStatus(v verdict.Report, runtimeName string) binaryStatus {
+	return func(i int, _ report.BinaryReport) string {
+		b := v.Binaries[i]
+		switch {
+		case !b.Compliant:
+			return fmt.Sprintf("❌ FIPS Status: NOT COMPLIANT (%s)", b.Reason)
+		case !v.RuntimeFIPSCapable:
+			return fmt.Sprintf("❌ FIPS Status: NOT COMPLIANT (%s not FIPS capable)", runtimeName)
+		default:
+			return "✅ FIPS Status: COMPLIANT (static analysis only)"
+		}
+	}
+}
+
+// printReports prints the per-binary reports, with the FIPS status of each
+// binary given by status.
+func printReports(reports []report.BinaryReport, status binaryStatus) {
 	fmt.Printf("\n=== Binary FIPS Check Report ===\n")
 	fmt.Printf("Total binaries scanned: %d\n\n", len(reports))
 
@@ -39,13 +295,14 @@ func printReports(reports []binarychecker.BinaryReport) {
 		return
 	}
 
-	// Check if host is FIPS capable
-	hostFIPSCapable := openssl.FIPSCapable()
-
 	// Count statistics
 	systemcryptoCount := 0
 	failedCount := 0
+	staticOnlyCount := 0
 	for _, report := range reports {
+		if report.GoBinaryDetails.StaticOnly {
+			staticOnlyCount++
+		}
 		if report.GoBinaryDetails.UseSystemcrypto {
 			systemcryptoCount++
 		}
@@ -55,13 +312,39 @@ func printReports(reports []binarychecker.BinaryReport) {
 	}
 
 	fmt.Printf("Binaries with systemcrypto: %d\n", systemcryptoCount)
-	fmt.Printf("Binaries that fail FIPS check: %d\n\n", failedCount)
+	fmt.Printf("Binaries that fail FIPS check: %d\n", failedCount)
+	if staticOnlyCount > 0 {
+		fmt.Printf("Binaries checked statically only: %d\n", staticOnlyCount)
+	}
+	fmt.Println()
 
 	// Print detailed report for each binary
 	for i, report := range reports {
 		fmt.Printf("─────────────────────────────────────────────────────\n")
-		fmt.Printf("[%d] Binary: %s\n", i+1, report.RelativePath)
+		if report.Entrypoint {
+			fmt.Printf("[%d] Binary: %s (image entrypoint)\n", i+1, report.RelativePath)
+		} else {
+			fmt.Printf("[%d] Binary: %s\n", i+1, report.RelativePath)
+		}
 		fmt.Printf("    Type: %s\n", report.Type)
+		if report.Digest != "" {
+			fmt.Printf("    Digest: %s\n", report.Digest)
+		}
+		if report.Cached {
+			fmt.Printf("    Result: from cache\n")
+		}
+		if report.SymlinkTarget != "" {
+			fmt.Printf("    Symlink To: %s\n", report.SymlinkTarget)
+		}
+		if report.AliasOf != "" {
+			fmt.Printf("    Same Binary As: %s (analyzed once)\n", report.AliasOf)
+		}
+		if len(report.Aliases) > 0 {
+			fmt.Printf("    Aliases: %s\n", strings.Join(report.Aliases, ", "))
+		}
+		if report.GoBinaryDetails.Architecture != "" {
+			fmt.Printf("    Architecture: %s\n", report.GoBinaryDetails.Architecture)
+		}
 
 		details := report.GoBinaryDetails
 		fmt.Printf("    Go Version: %s\n", details.GoVersion)
@@ -71,17 +354,9 @@ func printReports(reports []binarychecker.BinaryReport) {
 		fmt.Printf("    CGO Enabled: %t\n", details.CGOEnabled)
 		fmt.Printf("    Uses Systemcrypto: %t\n", details.UseSystemcrypto)
 		fmt.Printf("    Fails on FIPS Check: %t\n", details.FailsOnFIPSCheck)
+		printRuntimeOutcome(details)
 
-		// Report FIPS status
-		if !details.UseSystemcrypto {
-			fmt.Printf("    ❌ FIPS Status: NOT COMPLIANT (systemcrypto not in use)\n")
-		} else if details.FailsOnFIPSCheck {
-			fmt.Printf("    ❌ FIPS Status: NOT COMPLIANT (runtime check fails)\n")
-		} else if !hostFIPSCapable {
-			fmt.Printf("    ❌ FIPS Status: NOT COMPLIANT (host not FIPS capable)\n")
-		} else {
-			fmt.Printf("    ✅ FIPS Status: COMPLIANT\n")
-		}
+		fmt.Printf("    %s\n", status(i, report))
 
 		printRuntimeOutput(details.RuntimePanicLog)
 
@@ -98,6 +373,49 @@ func printReports(reports []binarychecker.BinaryReport) {
 		len(reports), systemcryptoCount, failedCount)
 }
 
+// printDiagnostics prints the paths a scan could not cover, if any
+func printDiagnostics(d report.ScanDiagnostics) {
+	if d.Complete() {
+		return
+	}
+	fmt.Printf("\n=== Scan Diagnostics ===\n")
+	fmt.Printf("⚠️  %d paths could not be scanned; binaries below them are missing from the report\n", d.Count)
+	for _, e := range d.Errors {
+		fmt.Printf("    - %s: %v\n", e.Path, e.Err)
+	}
+	if omitted := d.Count - len(d.Errors); omitted > 0 {
+		fmt.Printf("    ... and %d more\n", omitted)
+	}
+}
+
+// printVerdict prints the overall verdict for a root filesystem
+func printVerdict(r verdict.Report) {
+	fmt.Printf("\n=== Overall Verdict ===\n")
+	if r.Compliant {
+		fmt.Printf("✅ PASS: FIPS compliant\n")
+	} else {
+		fmt.Printf("❌ FAIL: NOT FIPS compliant\n")
+	}
+	for _, reason := range r.Reasons {
+		fmt.Printf("    - %s\n", reason)
+	}
+}
+
+// printRuntimeOutcome prints the classified result of the runtime check
+func printRuntimeOutcome(details report.GoBinaryReportDetails) {
+	fmt.Printf("    Runtime Outcome: %s", details.RuntimeOutcome)
+	switch {
+	case details.Signal != "":
+		fmt.Printf(" (signal: %s)", details.Signal)
+	case details.ExitCode >= 0:
+		fmt.Printf(" (exit code: %d)", details.ExitCode)
+	}
+	fmt.Println()
+	if details.PanicMessage != "" {
+		fmt.Printf("    Panic: %s\n", details.PanicMessage)
+	}
+}
+
 // printRuntimeOutput prints the runtime panic log with indentation
 func printRuntimeOutput(log string) {
 	if log != "" {
@@ -111,15 +429,61 @@ func printRuntimeOutput(log string) {
 	}
 }
 
-func checkHost() {
-	fmt.Printf("\n=== Host FIPS Environment Check ===\n")
-	fmt.Printf("OpenSSL Version: %s\n", openssl.VersionText())
-	fmt.Printf("FIPS Capable: %t\n", openssl.FIPSCapable())
-
-	if openssl.FIPSCapable() {
-		fmt.Printf("✅ Status: Host is FIPS capable\n")
+// printOSFIPS prints the kernel and OS FIPS mode signals
+func printOSFIPS(status osfips.Status) {
+	fmt.Printf("Host Root: %s\n", status.Root)
+	printSignal("Kernel FIPS Mode", status.KernelFIPS)
+	printSignal("Kernel Cmdline fips=1", status.KernelCmdline)
+	printSignal("System FIPS Marker", status.SystemFIPS)
+	printSignal("Crypto Policy", status.CryptoPolicy)
+	if status.Enabled() {
+		fmt.Printf("✅ Kernel is in FIPS mode\n")
 	} else {
-		fmt.Printf("⚠️  Status: Host is NOT FIPS capable\n")
+		fmt.Printf("⚠️  Kernel is NOT in FIPS mode\n")
 	}
+}
+
+func printSignal(name string, s osfips.Signal) {
+	var state string
+	switch {
+	case s.Err != nil:
+		state = fmt.Sprintf("error: %v", s.Err)
+	case !s.Found:
+		state = "not found"
+	case s.Value != "":
+		state = fmt.Sprintf("%s (FIPS: %t)", s.Value, s.Enabled)
+	default:
+		state = fmt.Sprintf("FIPS: %t", s.Enabled)
+	}
+	fmt.Printf("%s [%s]: %s\n", name, s.Source, state)
+}
+
+// printModule prints a validated module candidate with its CMVP certificate
+func printModule(d cmvp.Detected, catalog *cmvp.Catalog) {
+	name := fmt.Sprintf("%s %s", d.Module, d.Version)
+	if d.Path != "" {
+		name += fmt.Sprintf(" (%s)", d.Path)
+	}
+	fmt.Printf("FIPS Module: %s\n", name)
+
+	entry, ok := catalog.Lookup(d.Module, d.Version, d.Build)
+	switch {
+	case !ok:
+		fmt.Printf("    CMVP Certificate: not in catalog (updated %s)\n", catalog.Updated)
+	case entry.Certificate == 0:
+		fmt.Printf("    CMVP Certificate: %s (%s)\n", entry.Name, entry.Status)
+	default:
+		fmt.Printf("    CMVP Certificate: #%d %s (%s)\n", entry.Certificate, entry.Name, entry.Status)
+		fmt.Printf("    %s\n", entry.URL())
+	}
+}
+
+// checkHost prints the host environment, loading OpenSSL through the prober,
+// and reports whether the host is FIPS capable.
+func checkHost(ctx context.Context, host *hostOptions) bool {
+	fmt.Printf("\n=== Host FIPS Environment Check ===\n")
+	printOSFIPS(osfips.Detect(host.root))
+	capable := checkOpenSSL(ctx, host)
 	fmt.Println()
+	return capable
 }
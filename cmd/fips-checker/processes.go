@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/osfips"
+	"github.com/bahe-msft/fips-check/internal/procscan"
+)
+
+// errProcessesNotCompliant is returned when any running Go process fails its check.
+var errProcessesNotCompliant = errors.New("running processes are not FIPS compliant")
+
+// maxUninspected bounds the processes listed that could not be inspected.
+const maxUninspected = 10
+
+// runProcesses checks the Go processes running on the node through /proc.
+func runProcesses(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("processes", flag.ContinueOnError)
+	hostRoot := flags.String("host-root", "/", "root of the node filesystem whose /proc and kernel FIPS mode are read")
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New("processes: unexpected arguments")
+	}
+
+	kernelFIPS := osfips.Detect(*hostRoot).Enabled()
+	processes, err := procscan.Scan(ctx, filepath.Join(*hostRoot, "proc"))
+	if err != nil {
+		return err
+	}
+	if !printProcesses(processes, kernelFIPS) {
+		return errProcessesNotCompliant
+	}
+	return nil
+}
+
+// printProcesses prints the per-process reports of the Go processes and
+// reports whether none of them is known not to be compliant.
+func printProcesses(processes []procscan.Process, kernelFIPS bool) bool {
+	var goProcesses, uninspected []procscan.Process
+	for _, p := range processes {
+		switch {
+		case p.IsGo:
+			goProcesses = append(goProcesses, p)
+		case p.Err != nil:
+			uninspected = append(uninspected, p)
+		}
+	}
+	// Go processes that could not be fully inspected are reported in full, but counted as not inspected
+	uninspectedGo := 0
+	for _, p := range goProcesses {
+		if p.Err != nil {
+			uninspectedGo++
+		}
+	}
+
+	fmt.Printf("\n=== Process FIPS Check Report ===\n")
+	fmt.Printf("Kernel FIPS Mode: %t\n", kernelFIPS)
+	fmt.Printf("Processes: %d | Go processes: %d | Not inspected: %d\n\n",
+		len(processes), len(goProcesses), len(uninspected)+uninspectedGo)
+
+	compliantCount, notCompliantCount := 0, 0
+	for i, p := range goProcesses {
+		fmt.Printf("─────────────────────────────────────────────────────\n")
+		fmt.Printf("[%d] PID %d: %s\n", i+1, p.PID, p.Exe)
+		if p.Cgroup != "" {
+			fmt.Printf("    Cgroup: %s\n", p.Cgroup)
+		}
+		if p.ContainerID != "" {
+			fmt.Printf("    Container ID: %s\n", p.ContainerID)
+		}
+		if p.SameExecutableAs != 0 {
+			fmt.Printf("    Same Executable As: PID %d (analyzed once)\n", p.SameExecutableAs)
+		}
+
+		details := p.GoBinaryDetails
+		fmt.Printf("    Go Version: %s\n", details.GoVersion)
+		if details.Module != "" {
+			fmt.Printf("    Module: %s\n", details.Module)
+		}
+		fmt.Printf("    CGO Enabled: %t\n", details.CGOEnabled)
+		fmt.Printf("    Uses Systemcrypto: %t\n", details.UseSystemcrypto)
+		fmt.Printf("    Loaded libcrypto: %s\n", listOrNone(p.Libcrypto))
+		fmt.Printf("    Loaded FIPS Providers: %s\n", listOrNone(p.FIPSProviders))
+		if p.GOFIPSSet {
+			fmt.Printf("    GOFIPS: %s\n", p.GOFIPS)
+		} else {
+			fmt.Printf("    GOFIPS: not set\n")
+		}
+
+		switch status, reason := p.Check(kernelFIPS); status {
+		case procscan.StatusCompliant:
+			compliantCount++
+			fmt.Printf("    ✅ FIPS Status: COMPLIANT\n")
+		case procscan.StatusNotCompliant:
+			notCompliantCount++
+			fmt.Printf("    ❌ FIPS Status: NOT COMPLIANT (%s)\n", reason)
+		default:
+			fmt.Printf("    ⚠️  FIPS Status: INCONCLUSIVE (%s)\n", reason)
+		}
+		fmt.Println()
+	}
+
+	if len(uninspected) > 0 {
+		fmt.Printf("─────────────────────────────────────────────────────\n")
+		fmt.Printf("⚠️  %d processes could not be inspected; run as root to check them\n", len(uninspected))
+		for _, p := range uninspected[:min(len(uninspected), maxUninspected)] {
+			if p.Exe != "" {
+				fmt.Printf("    - PID %d (%s): %v\n", p.PID, p.Exe, p.Err)
+			} else {
+				fmt.Printf("    - PID %d: %v\n", p.PID, p.Err)
+			}
+		}
+		if omitted := len(uninspected) - maxUninspected; omitted > 0 {
+			fmt.Printf("    ... and %d more\n", omitted)
+		}
+	}
+
+	fmt.Printf("─────────────────────────────────────────────────────\n")
+	fmt.Printf("Summary:\n")
+	fmt.Printf("  Go processes: %d | Compliant: %d | Not compliant: %d | Not inspected: %d\n",
+		len(goProcesses), compliantCount, notCompliantCount, uninspectedGo)
+	return notCompliantCount == 0
+}
+
+// listOrNone joins items with commas, or returns "none" if there are none.
+func listOrNone(items []string) string {
+	if len(items) == 0 {
+		return "none"
+	}
+	return strings.Join(items, ", ")
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/binarychecker"
+)
+
+// progressInterval limits how often the progress bar is redrawn.
+const progressInterval = 100 * time.Millisecond
+
+// progressBarWidth is the number of cells in the progress bar.
+const progressBarWidth = 30
+
+// progressFlag registers the -progress flag, which defaults to on when stderr is a terminal.
+func progressFlag(flags *flag.FlagSet) *bool {
+	return flags.Bool("progress", isTerminal(os.Stderr), "render scan progress on stderr (default: on when stderr is a terminal)")
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// progressBar renders scan progress on a single, redrawn line of stderr.
+type progressBar struct {
+	mu      sync.Mutex
+	last    time.Time
+	drawn   bool
+	enabled bool
+}
+
+func newProgressBar(enabled bool) *progressBar {
+	return &progressBar{enabled: enabled}
+}
+
+// update redraws the bar, at most every progressInterval unless the scan is done.
+func (b *progressBar) update(p binarychecker.Progress) {
+	if !b.enabled {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	finished := p.WalkDone && p.Completed == p.Candidates
+	if !finished && time.Since(b.last) < progressInterval {
+		return
+	}
+	b.last = time.Now()
+	b.drawn = true
+
+	if !p.WalkDone {
+		fmt.Fprintf(os.Stderr, "\r\033[KScanning: %d files walked, %d Go binaries found, %d checked, %d probes running",
+			p.FilesWalked, p.Candidates, p.Completed, p.ProbesRunning)
+		return
+	}
+	filled := progressBarWidth
+	if p.Candidates > 0 {
+		filled = progressBarWidth * p.Completed / p.Candidates
+	}
+	fmt.Fprintf(os.Stderr, "\r\033[K[%s%s] %d/%d Go binaries checked, %d probes running",
+		strings.Repeat("=", filled), strings.Repeat(" ", progressBarWidth-filled),
+		p.Completed, p.Candidates, p.ProbesRunning)
+}
+
+// clear removes the bar so that the report starts on a clean line.
+func (b *progressBar) clear() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.drawn {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		b.drawn = false
+	}
+}
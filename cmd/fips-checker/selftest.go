@@ -0,0 +1,70 @@
+//go:build cgo
+
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/selftest"
+)
+
+// errSelfTestFailed is returned when a self-test fails, to exit non-zero.
+var errSelfTestFailed = errors.New("self-tests failed")
+
+// runSelfTest loads the host's OpenSSL and runs the cryptographic self-tests.
+func runSelfTest(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("selftest", flag.ContinueOnError)
+	host := hostFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	if err := host.prober.Init(ctx); err != nil {
+		return err
+	}
+	report, err := selftest.Run(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("\n=== Cryptographic Self-Tests ===\n")
+	fmt.Printf("Library: %s\n", report.Library)
+	if report.FIPSMode {
+		fmt.Printf("FIPS Mode: enabled\n\n")
+	} else {
+		fmt.Printf("FIPS Mode: could not be enabled: %v\n\n", report.FIPSModeErr)
+	}
+
+	for _, r := range report.Results {
+		expectation := "approved"
+		if !r.Approved {
+			expectation = "must be rejected"
+		}
+		status := "✅ PASS"
+		if !r.Passed {
+			status = "❌ FAIL"
+		}
+		fmt.Printf("%s  %-20s %-18s %10s\n", status, r.Name, expectation, r.Duration.Round(time.Microsecond))
+		if r.Err != nil {
+			fmt.Printf("        %v\n", r.Err)
+		}
+	}
+	fmt.Println()
+	if report.FIPSRestoreErr != nil {
+		fmt.Printf("⚠️  FIPS mode could not be disabled after the tests: %v\n", report.FIPSRestoreErr)
+	}
+
+	if !report.Passed() {
+		fmt.Printf("❌ Status: Self-tests FAILED\n")
+		return errSelfTestFailed
+	}
+	fmt.Printf("✅ Status: All self-tests passed in FIPS mode\n")
+	return nil
+}
@@ -14,6 +14,10 @@ func main() {
 	// Check host FIPS capabilities
 	hostInfo := fipscheck.CheckHostFIPS()
 	fmt.Printf("Host FIPS Info:\n")
+	if hostInfo.Error != nil {
+		fmt.Printf("  Error: %v\n", hostInfo.Error)
+	}
+	fmt.Printf("  Library: %s\n", hostInfo.Library)
 	fmt.Printf("  OpenSSL Version: %s\n", hostInfo.OpenSSLVersion)
 	fmt.Printf("  FIPS Capable: %t\n", hostInfo.FIPSCapable)
 	fmt.Println()
@@ -42,6 +46,7 @@ func main() {
 		fmt.Printf("  CGO Enabled: %t\n", details.CGOEnabled)
 		fmt.Printf("  Uses Systemcrypto: %t\n", details.UseSystemcrypto)
 		fmt.Printf("  Fails FIPS Check: %t\n", details.FailsOnFIPSCheck)
+		fmt.Printf("  Runtime Outcome: %s\n", details.RuntimeOutcome)
 
 		// Determine final FIPS compliance using SDK helper
 		isCompliant := fipscheck.IsBinaryFIPSCompliant(details, hostInfo.FIPSCapable)
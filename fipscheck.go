@@ -1,92 +1,273 @@
-//go:build cgo
-
 // Package fipscheck provides FIPS compliance checking functionality for Go binaries.
 // This package exposes the functionality from internal packages to allow external
 // repositories to use the FIPS checking capabilities.
+//
+// Static analysis of binaries is pure Go and works in builds with CGO_ENABLED=0.
+// Host checks need cgo to load OpenSSL; see HostCheckSupported.
 package fipscheck
 
 import (
 	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"iter"
+	"os"
 
 	"github.com/bahe-msft/fips-check/internal/binarychecker"
-	_ "github.com/bahe-msft/fips-check/internal/opensslsetup" // Initialize OpenSSL
-	"github.com/golang-fips/openssl/v2"
+	"github.com/bahe-msft/fips-check/report"
 )
 
 // BinaryReport contains the FIPS compliance information for a binary file.
-// This mirrors the internal BinaryReport structure to provide external access.
-type BinaryReport struct {
-	// RelativePath is the path of the binary relative to the scan root
-	RelativePath string
-	// Type indicates the type of binary (e.g., "gobinary")
-	Type            string
-	GoBinaryDetails GoBinaryReportDetails
-	// Error contains any error that occurred while scanning this binary
-	Error error
-}
+// It is defined in package report, which also provides its JSON encoding.
+type BinaryReport = report.BinaryReport
 
 // GoBinaryReportDetails contains detailed information about a Go binary's FIPS capabilities.
-type GoBinaryReportDetails struct {
-	GoVersion        string
-	Module           string
-	UseSystemcrypto  bool
-	CGOEnabled       bool
-	FailsOnFIPSCheck bool   // Indicates if the binary fails when run with GOFIPS=1
-	RuntimePanicLog  string // Captures the panic log from runtime FIPS check
-}
+type GoBinaryReportDetails = report.GoBinaryReportDetails
+
+// RuntimeOutcome classifies how a binary behaved when it was executed with GOFIPS=1.
+type RuntimeOutcome = report.RuntimeOutcome
 
+// Runtime outcomes reported in GoBinaryReportDetails.RuntimeOutcome.
+const (
+	RuntimeOutcomeUnknown            = report.RuntimeOutcomeUnknown
+	RuntimeOutcomeFIPSPanic          = report.RuntimeOutcomeFIPSPanic
+	RuntimeOutcomeOpenSSLLoadFailure = report.RuntimeOutcomeOpenSSLLoadFailure
+	RuntimeOutcomeMissingLibcrypto   = report.RuntimeOutcomeMissingLibcrypto
+	RuntimeOutcomeGlibcMismatch      = report.RuntimeOutcomeGlibcMismatch
+	RuntimeOutcomeWrongArchitecture  = report.RuntimeOutcomeWrongArchitecture
+	RuntimeOutcomeNonFIPSPanic       = report.RuntimeOutcomeNonFIPSPanic
+	RuntimeOutcomeCleanExit          = report.RuntimeOutcomeCleanExit
+	RuntimeOutcomeNonZeroExit        = report.RuntimeOutcomeNonZeroExit
+	RuntimeOutcomeSignal             = report.RuntimeOutcomeSignal
+	RuntimeOutcomeTimeout            = report.RuntimeOutcomeTimeout
+	RuntimeOutcomeSkipped            = report.RuntimeOutcomeSkipped
+	RuntimeOutcomeMissingLibrary     = report.RuntimeOutcomeMissingLibrary
+)
 
 // CheckBinaries recursively scans the filesystem starting from the given path
 // and checks all binaries for FIPS compliance in parallel.
 // It returns a slice of BinaryReport containing the results for each binary found.
-func CheckBinaries(ctx context.Context, path string) ([]BinaryReport, error) {
-	internalReports, err := binarychecker.Check(ctx, path)
+// The scan can be tuned with options such as WithConcurrency and WithExclude.
+func CheckBinaries(ctx context.Context, path string, opts ...Option) ([]BinaryReport, error) {
+	return binarychecker.CheckWithOptions(ctx, path, newScanConfig(opts).options)
+}
+
+// CheckBinariesStatic is like CheckBinaries but never executes the binaries.
+// Every report is based on static analysis only and has StaticOnly set.
+func CheckBinariesStatic(ctx context.Context, path string, opts ...Option) ([]BinaryReport, error) {
+	return binarychecker.CheckWithOptions(ctx, path, newScanConfig(opts, WithRuntimeProbe(false)).options)
+}
+
+// CheckBinariesFS is like CheckBinariesStatic but scans the tree of fsys,
+// such as a tar or zip archive, an in-memory filesystem or a layered image
+// filesystem, without extracting it to disk. RelativePath is the path in fsys.
+// The runtime check needs executable OS files and is therefore always skipped.
+func CheckBinariesFS(ctx context.Context, fsys fs.FS, opts ...Option) ([]BinaryReport, error) {
+	return binarychecker.CheckFS(ctx, fsys, newScanConfig(opts, WithRuntimeProbe(false)).options)
+}
+
+// Scan is like CheckBinaries but returns the whole scan result, including
+// diagnostics of the paths that could not be scanned, such as directories
+// without read permission. With WithStrict, an incomplete scan fails with
+// an error wrapping report.ErrIncompleteScan, and the result is returned
+// along with the error.
+func Scan(ctx context.Context, path string, opts ...Option) (report.Scan, error) {
+	return binarychecker.Scan(ctx, path, newScanConfig(opts).options)
+}
+
+// ScanFS is like Scan but scans the tree of fsys, like CheckBinariesFS.
+func ScanFS(ctx context.Context, fsys fs.FS, opts ...Option) (report.Scan, error) {
+	return binarychecker.ScanFS(ctx, fsys, newScanConfig(opts, WithRuntimeProbe(false)).options)
+}
+
+// AnalyzeFile statically analyzes the single Go binary at path. Unlike
+// CheckBinaries it does not walk a tree and never executes the binary, so
+// the report has StaticOnly set. RelativePath is set to path.
+// Pass the report's details to IsBinaryFIPSCompliant for a verdict.
+func AnalyzeFile(ctx context.Context, path string) (BinaryReport, error) {
+	f, err := os.Open(path)
 	if err != nil {
-		return nil, err
+		return BinaryReport{}, err
 	}
+	defer f.Close()
 
-	// Convert internal reports to public SDK reports
-	reports := make([]BinaryReport, len(internalReports))
-	for i, report := range internalReports {
-		reports[i] = BinaryReport{
-			RelativePath: report.RelativePath,
-			Type:         report.Type,
-			GoBinaryDetails: GoBinaryReportDetails{
-				GoVersion:        report.GoBinaryDetails.GoVersion,
-				Module:           report.GoBinaryDetails.Module,
-				UseSystemcrypto:  report.GoBinaryDetails.UseSystemcrypto,
-				CGOEnabled:       report.GoBinaryDetails.CGOEnabled,
-				FailsOnFIPSCheck: report.GoBinaryDetails.FailsOnFIPSCheck,
-				RuntimePanicLog:  report.GoBinaryDetails.RuntimePanicLog,
-			},
-			Error: report.Error,
-		}
+	info, err := f.Stat()
+	if err != nil {
+		return BinaryReport{}, err
 	}
+	r, err := AnalyzeReader(ctx, f, info.Size())
+	r.RelativePath = path
+	return r, err
+}
+
+// AnalyzeReader is like AnalyzeFile for a binary that is not on disk, such as
+// a blob streamed from a registry or an artifact store. r must hold size bytes.
+// The report has an empty RelativePath.
+func AnalyzeReader(ctx context.Context, r io.ReaderAt, size int64) (BinaryReport, error) {
+	details, err := binarychecker.Analyze(ctx, r, size)
+	if err != nil {
+		return BinaryReport{}, err
+	}
+	return BinaryReport{
+		Type:            string(AnalyzerGoBinary),
+		GoBinaryDetails: details,
+	}, nil
+}
 
-	return reports, nil
+// ScanProgress is a snapshot of a running scan.
+type ScanProgress struct {
+	// FilesWalked is the number of files visited so far
+	FilesWalked int
+	// Candidates is the number of Go binaries found so far
+	Candidates int
+	// ProbesRunning is the number of runtime FIPS checks running right now
+	ProbesRunning int
+	// Completed is the number of binaries whose report is ready
+	Completed int
+	// WalkDone indicates the tree has been walked, so Candidates is final
+	WalkDone bool
+}
+
+// ScanBinaries is a streaming variant of CheckBinaries. It yields each report
+// as soon as the binary has been checked, while the tree is still being
+// walked, so reports arrive in completion order rather than path order.
+// A walk error or the cancellation of ctx is yielded last, with an empty report,
+// as is report.ErrIncompleteScan with WithStrict. Breaking out of the loop
+// cancels the remaining checks.
+func ScanBinaries(ctx context.Context, path string, opts ...Option) iter.Seq2[BinaryReport, error] {
+	return binarychecker.Stream(ctx, path, newScanConfig(opts).options)
+}
+
+// ScanBinariesFS is a streaming variant of CheckBinariesFS.
+// As there, files in fsys are never executed.
+func ScanBinariesFS(ctx context.Context, fsys fs.FS, opts ...Option) iter.Seq2[BinaryReport, error] {
+	return binarychecker.StreamFS(ctx, fsys, newScanConfig(opts, WithRuntimeProbe(false)).options)
 }
 
 // HostFIPSInfo contains information about the host's FIPS capabilities.
 type HostFIPSInfo struct {
 	OpenSSLVersion string
 	FIPSCapable    bool
+	// Library is the libcrypto that was loaded, or attempted to be loaded
+	Library string
+	// Candidates lists the well-known libcrypto libraries found on the host
+	Candidates []LibraryCandidate
+
+	// The following fields describe the OpenSSL 3 provider configuration.
+	// They are empty for OpenSSL 1.x, which has no providers.
+	Providers []ProviderInfo
+	// FIPSProviderActive indicates the "fips" provider is activated
+	FIPSProviderActive bool
+	// DefaultPropertiesFIPS indicates the default properties contain fips=yes
+	DefaultPropertiesFIPS bool
+	// DefaultProperties is the default_properties value from openssl.cnf
+	DefaultProperties string
+	// ConfigDir is OPENSSLDIR as compiled into libcrypto
+	ConfigDir string
+	// ConfigFile is the openssl.cnf in effect, honoring OPENSSL_CONF
+	ConfigFile string
+	// FIPSModuleConfig is the fipsmodule.cnf referenced by openssl.cnf
+	FIPSModuleConfig string
+	// ModulesDir is the provider module directory, honoring OPENSSL_MODULES
+	ModulesDir string
+
+	// The following fields report whether the node runs in FIPS mode, as
+	// seen by the kernel and the OS. They are read from files below HostRoot
+	// and are available in builds without cgo.
+	HostRoot string
+	// KernelFIPSEnabled is /proc/sys/crypto/fips_enabled
+	KernelFIPSEnabled FIPSSignal
+	// KernelCmdlineFIPS is the fips=1 flag in /proc/cmdline
+	KernelCmdlineFIPS FIPSSignal
+	// SystemFIPSMarker is the /etc/system-fips marker file
+	SystemFIPSMarker FIPSSignal
+	// CryptoPolicy is the policy in /etc/crypto-policies/config
+	CryptoPolicy FIPSSignal
+
+	// FIPSModules lists the validated module candidates among the providers,
+	// such as the OpenSSL FIPS provider, with their CMVP certificates
+	FIPSModules []FIPSModule
+
+	// Error is set if the host check could not be performed
+	Error error
 }
 
-// CheckHostFIPS returns information about the host's FIPS capabilities.
-// This function checks the OpenSSL version and FIPS capability of the current host.
-func CheckHostFIPS() HostFIPSInfo {
-	return HostFIPSInfo{
-		OpenSSLVersion: openssl.VersionText(),
-		FIPSCapable:    openssl.FIPSCapable(),
-	}
+// FIPSModule is a cryptographic module that may hold a CMVP validation.
+type FIPSModule struct {
+	// Module is the module family, e.g. "openssl-fips-provider" or "symcrypt-openssl"
+	Module  string
+	Version string
+	// Build is the provider build info, which identifies distro builds
+	Build string
+	// Path is the module file, if it was found on disk
+	Path string
+	// Certificate is the matching CMVP certificate, nil if the module
+	// version is not in the catalog
+	Certificate *CMVPCertificate
 }
 
+// CMVPCertificate describes a CMVP certificate from the catalog.
+type CMVPCertificate struct {
+	// Number is the certificate number, 0 while the validation is in process
+	Number int
+	// Name is the module name as listed on the certificate
+	Name string
+	// Status is "active", "historical" or "in-process"
+	Status string
+	// URL is the certificate page on the NIST site
+	URL string
+}
+
+// FIPSSignal is a single kernel or OS indicator of FIPS mode.
+type FIPSSignal struct {
+	// Source is the file the signal was read from, relative to the host root
+	Source string
+	// Found indicates the file exists
+	Found bool
+	// Enabled indicates the signal reports FIPS mode
+	Enabled bool
+	// Value is the relevant content, e.g. "1" or the crypto policy name
+	Value string
+	// Error is set if the file exists but could not be read
+	Error error
+}
+
+// ProviderInfo describes an activated OpenSSL 3 provider.
+type ProviderInfo struct {
+	Name      string
+	Version   string
+	BuildInfo string
+	// Active reports the provider's self-reported status
+	Active bool
+}
+
+// ErrHostCheckUnavailable is reported by CheckHostFIPS when the package was
+// built without cgo and therefore cannot load OpenSSL.
+var ErrHostCheckUnavailable = errors.New("fipscheck: host FIPS check requires cgo")
+
+// Errors recorded in BinaryReport.Error, see package report. Test for them
+// with errors.Is, and for *ExecError with errors.As. They mark scanner
+// failures, as opposed to compliance failures, which are reported in
+// GoBinaryReportDetails.
+var (
+	ErrNotELF              = report.ErrNotELF
+	ErrNoBuildInfo         = report.ErrNoBuildInfo
+	ErrForeignArch         = report.ErrForeignArch
+	ErrRuntimeProbeTimeout = report.ErrRuntimeProbeTimeout
+	ErrPermission          = report.ErrPermission
+)
+
+// ExecError describes a runtime check that did not produce a conclusive outcome.
+type ExecError = report.ExecError
 
 // IsBinaryFIPSCompliant determines if a binary is FIPS compliant based on the report details.
 // A binary is considered FIPS compliant if:
 // - It uses systemcrypto (GOEXPERIMENT=systemcrypto)
 // - It doesn't fail the runtime FIPS check
 // - The host system is FIPS capable
+//
+// For binaries with StaticOnly set, the runtime check did not contribute to the verdict.
 func IsBinaryFIPSCompliant(details GoBinaryReportDetails, hostFIPSCapable bool) bool {
 	if !details.UseSystemcrypto {
 		return false
@@ -95,4 +276,4 @@ func IsBinaryFIPSCompliant(details GoBinaryReportDetails, hostFIPSCapable bool)
 		return false
 	}
 	return hostFIPSCapable
-}
\ No newline at end of file
+}
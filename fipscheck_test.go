@@ -4,10 +4,16 @@ package fipscheck
 
 import (
 	"context"
+	"encoding/binary"
+	"errors"
 	"os"
 	"path/filepath"
+	"runtime"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
 )
 
 func TestCheckHostFIPS(t *testing.T) {
@@ -289,4 +295,168 @@ func copyFile(src, dst string) error {
 		return err
 	}
 	return os.WriteFile(dst, input, 0644)
-}
\ No newline at end of file
+}
+
+func TestCheckBinariesForeignArchitecture(t *testing.T) {
+	// The test binary itself is a Go binary; rewrite its ELF machine field so
+	// it looks like it was built for another architecture.
+	data := testutil.TestBinary(t)
+	if len(data) < 20 || string(data[:4]) != "\x7fELF" {
+		t.Skip("Test binary is not an ELF file")
+	}
+
+	foreignMachine, foreignArch := uint16(183), "arm64" // EM_AARCH64
+	if runtime.GOARCH == "arm64" {
+		foreignMachine, foreignArch = 62, "amd64" // EM_X86_64
+	}
+	binary.LittleEndian.PutUint16(data[18:20], foreignMachine)
+
+	tempDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(tempDir, "foreign"), data, 0755); err != nil {
+		t.Fatalf("Failed to write foreign binary: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reports, err := CheckBinaries(ctx, tempDir)
+	if err != nil {
+		t.Fatalf("CheckBinaries failed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+
+	details := reports[0].GoBinaryDetails
+	if details.Architecture != foreignArch {
+		t.Errorf("Expected architecture %s, got %q", foreignArch, details.Architecture)
+	}
+	if !details.ForeignArchitecture {
+		t.Error("Expected binary to be reported as foreign architecture")
+	}
+	if !details.StaticOnly {
+		t.Error("Expected static-only verdict for foreign binary")
+	}
+	if details.RuntimeOutcome != RuntimeOutcomeSkipped {
+		t.Errorf("Expected runtime outcome %s, got %s", RuntimeOutcomeSkipped, details.RuntimeOutcome)
+	}
+	if !errors.Is(reports[0].Error, ErrForeignArch) {
+		t.Errorf("Expected ErrForeignArch for foreign binary, got %v", reports[0].Error)
+	}
+}
+
+func TestHostProber(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var prober HostProber
+	if err := prober.Init(ctx); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	info := prober.Info()
+	if info.Library == "" {
+		t.Error("Expected the loaded library to be reported")
+	}
+	if info.OpenSSLVersion == "" {
+		t.Error("Expected non-empty OpenSSL version")
+	}
+
+	candidates := prober.Candidates()
+	if len(candidates) == 0 {
+		t.Fatal("Expected well-known libraries to be reported as candidates")
+	}
+	found := false
+	for _, c := range candidates {
+		t.Logf("Candidate %s: exists=%t fips=%t", c.Name, c.Exists, c.FIPS)
+		if c.Exists {
+			found = true
+		}
+	}
+	if !found && info.Library != "libcrypto.so" {
+		t.Error("Expected at least one existing candidate")
+	}
+
+	if err := prober.Reprobe(ctx); err != nil {
+		t.Errorf("Reprobe failed: %v", err)
+	}
+	if prober.Info().Library != info.Library {
+		t.Errorf("Expected Reprobe to keep library %s, got %s", info.Library, prober.Info().Library)
+	}
+}
+
+func TestHostProviders(t *testing.T) {
+	var prober HostProber
+	if err := prober.Init(context.Background()); err != nil {
+		t.Fatalf("Init failed: %v", err)
+	}
+
+	info := prober.Info()
+	if !strings.HasPrefix(info.OpenSSLVersion, "OpenSSL 3") {
+		t.Skipf("Providers require OpenSSL 3, have %s", info.OpenSSLVersion)
+	}
+	if len(info.Providers) == 0 {
+		t.Fatal("Expected at least one provider to be reported")
+	}
+	for _, p := range info.Providers {
+		t.Logf("Provider %s %s active=%t", p.Name, p.Version, p.Active)
+		if p.Name == "fips" && p.Active != info.FIPSProviderActive {
+			t.Errorf("Expected FIPSProviderActive to match the fips provider status")
+		}
+	}
+	if info.ConfigDir == "" {
+		t.Error("Expected OPENSSLDIR to be reported")
+	}
+	if info.ModulesDir == "" {
+		t.Error("Expected the modules directory to be reported")
+	}
+}
+
+func TestHostProberMissingLibrary(t *testing.T) {
+	prober := HostProber{Library: "/path/that/does/not/exist/libcrypto.so.3"}
+
+	err := prober.Init(context.Background())
+	if err == nil {
+		t.Fatal("Expected an error for a missing library")
+	}
+	t.Logf("Init error: %v", err)
+
+	info := prober.Info()
+	if info.Error == nil {
+		t.Error("Expected the error to be reported in HostFIPSInfo")
+	}
+	if info.FIPSCapable {
+		t.Error("Expected host not to be FIPS capable without a loaded library")
+	}
+}
+
+func TestRunSelfTests(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	report, err := RunSelfTests(ctx)
+	if err != nil {
+		t.Fatalf("RunSelfTests failed: %v", err)
+	}
+	if len(report.Results) == 0 {
+		t.Fatal("Expected self-test results")
+	}
+	if !report.FIPSMode {
+		t.Logf("FIPS mode unavailable: %v", report.FIPSModeError)
+	}
+
+	for _, r := range report.Results {
+		t.Logf("%s: approved=%t passed=%t duration=%s err=%v", r.Name, r.Approved, r.Passed, r.Duration, r.Error)
+		// Known answers must match with or without FIPS mode; rejection of
+		// non-approved algorithms is only expected in FIPS mode.
+		if r.Approved && !r.Passed {
+			t.Errorf("Approved algorithm %s failed: %v", r.Name, r.Error)
+		}
+		if !r.Approved && report.FIPSMode && !r.Passed {
+			t.Errorf("Non-approved algorithm %s was not rejected in FIPS mode", r.Name)
+		}
+	}
+	if !report.FIPSMode && report.Passed() {
+		t.Error("Expected the report not to pass outside FIPS mode")
+	}
+}
@@ -0,0 +1,207 @@
+package fipscheck
+
+import (
+	"context"
+	"strconv"
+	"sync"
+
+	"github.com/bahe-msft/fips-check/internal/cache"
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/osfips"
+)
+
+// LibraryCandidate is a libcrypto shared library considered while probing the host.
+type LibraryCandidate struct {
+	// Name is the library name passed to dlopen, e.g. "libcrypto.so.3"
+	Name string
+	// Exists indicates the library could be loaded
+	Exists bool
+	// FIPS indicates the library runs in FIPS mode by default
+	FIPS bool
+}
+
+// HostProber loads the host's OpenSSL explicitly and reports its FIPS capabilities.
+// Unlike importing the Go crypto backend, a failure to load OpenSSL is returned
+// as an error instead of panicking. The zero value is ready to use.
+type HostProber struct {
+	// Library selects a specific libcrypto to load, either a library name
+	// such as "libcrypto.so.3" or an absolute path. When empty, the library is
+	// chosen like the Go crypto backend does: GO_OPENSSL_VERSION_OVERRIDE first,
+	// then the highest FIPS-enabled well-known version, then the highest available one.
+	// Changes after the first call to Init have no effect.
+	Library string
+	// Root is the host root the kernel and OS FIPS signals are read from,
+	// e.g. the mount point of a node filesystem. When empty, "/" is used.
+	// OpenSSL is always loaded from the running host.
+	Root string
+	// CMVPCatalog is a catalog file whose entries take precedence over the
+	// catalog of CMVP certificates embedded in the package. When empty, only
+	// the embedded catalog is used.
+	CMVPCatalog string
+
+	mu       sync.Mutex
+	impl     *hostProbe
+	osStatus *osfips.Status
+	catalog  *cmvp.Catalog
+}
+
+func (p *HostProber) probe() *hostProbe {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.impl == nil {
+		p.impl = newHostProbe(p.Library)
+	}
+	return p.impl
+}
+
+// Init searches the host for libcrypto and loads the selected library.
+// Only the first call probes the host; later calls return the same result.
+// OpenSSL can be loaded only once per process, so all probers share the
+// library loaded first.
+func (p *HostProber) Init(ctx context.Context) error {
+	if err := p.loadCatalog(); err != nil {
+		return err
+	}
+	p.detectOS(false)
+	return p.probe().init(ctx)
+}
+
+// Reprobe searches the host again and refreshes the reported status.
+// A library that is already loaded stays loaded.
+//
+// Probing other libraries temporarily rebinds the OpenSSL functions used by
+// the process, so Reprobe must not run concurrently with other OpenSSL calls.
+func (p *HostProber) Reprobe(ctx context.Context) error {
+	if err := p.loadCatalog(); err != nil {
+		return err
+	}
+	p.detectOS(true)
+	return p.probe().reprobe(ctx)
+}
+
+// loadCatalog loads the CMVP catalog once.
+func (p *HostProber) loadCatalog() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.catalog != nil {
+		return nil
+	}
+	if p.CMVPCatalog == "" {
+		p.catalog = cmvp.Default()
+		return nil
+	}
+	catalog, err := cmvp.Load(p.CMVPCatalog)
+	if err != nil {
+		return err
+	}
+	p.catalog = catalog
+	return nil
+}
+
+// detectOS reads the kernel and OS FIPS signals, unless already read and !force.
+func (p *HostProber) detectOS(force bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.osStatus == nil || force {
+		status := osfips.Detect(p.Root)
+		p.osStatus = &status
+	}
+}
+
+// Info returns the host information gathered by the last probe.
+func (p *HostProber) Info() HostFIPSInfo {
+	info := p.probe().info()
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.osStatus != nil {
+		info.HostRoot = p.osStatus.Root
+		info.KernelFIPSEnabled = fipsSignal(p.osStatus.KernelFIPS)
+		info.KernelCmdlineFIPS = fipsSignal(p.osStatus.KernelCmdline)
+		info.SystemFIPSMarker = fipsSignal(p.osStatus.SystemFIPS)
+		info.CryptoPolicy = fipsSignal(p.osStatus.CryptoPolicy)
+	}
+	if p.catalog != nil {
+		for _, provider := range info.Providers {
+			module, ok := cmvp.ProviderModule(provider.Name)
+			if !ok {
+				continue
+			}
+			info.FIPSModules = append(info.FIPSModules, fipsModule(p.catalog, cmvp.Detected{
+				Module:  module,
+				Version: provider.Version,
+				Build:   provider.BuildInfo,
+			}))
+		}
+	}
+	return info
+}
+
+// fipsModule looks a detected module up in the catalog.
+func fipsModule(catalog *cmvp.Catalog, d cmvp.Detected) FIPSModule {
+	module := FIPSModule{
+		Module:  string(d.Module),
+		Version: d.Version,
+		Build:   d.Build,
+		Path:    d.Path,
+	}
+	if entry, ok := catalog.Lookup(d.Module, d.Version, d.Build); ok {
+		module.Certificate = &CMVPCertificate{
+			Number: entry.Certificate,
+			Name:   entry.Name,
+			Status: string(entry.Status),
+			URL:    entry.URL(),
+		}
+	}
+	return module
+}
+
+func fipsSignal(s osfips.Signal) FIPSSignal {
+	return FIPSSignal{
+		Source:  s.Source,
+		Found:   s.Found,
+		Enabled: s.Enabled,
+		Value:   s.Value,
+		Error:   s.Err,
+	}
+}
+
+// Candidates returns every well-known libcrypto found by the last probe,
+// with its exists and FIPS status.
+func (p *HostProber) Candidates() []LibraryCandidate {
+	return p.Info().Candidates
+}
+
+// defaultHostProber backs CheckHostFIPS.
+var defaultHostProber HostProber
+
+// CheckHostFIPS returns information about the host's FIPS capabilities.
+// This function checks the OpenSSL version and FIPS capability of the current host,
+// and whether the kernel and OS run in FIPS mode.
+// If OpenSSL cannot be loaded, the returned info has its Error field set.
+func CheckHostFIPS() HostFIPSInfo {
+	_ = defaultHostProber.Init(context.Background())
+	return defaultHostProber.Info()
+}
+
+// hostFingerprint identifies the host's crypto runtime, which the outcome of
+// runtime FIPS checks depends on. It loads OpenSSL like CheckHostFIPS.
+func hostFingerprint() string {
+	info := CheckHostFIPS()
+	parts := []string{
+		info.Library,
+		info.OpenSSLVersion,
+		strconv.FormatBool(info.FIPSCapable),
+		strconv.FormatBool(info.KernelFIPSEnabled.Enabled),
+	}
+	for _, p := range info.Providers {
+		parts = append(parts, p.Name+" "+p.Version)
+	}
+	if info.Error != nil {
+		parts = append(parts, info.Error.Error())
+	}
+	return cache.Fingerprint(parts...)
+}
@@ -0,0 +1,85 @@
+//go:build cgo
+
+package fipscheck
+
+import (
+	"context"
+
+	"github.com/bahe-msft/fips-check/internal/opensslsetup"
+	"github.com/bahe-msft/fips-check/internal/selftest"
+)
+
+// HostCheckSupported reports whether this build can check the host's
+// OpenSSL FIPS capabilities. It is false in builds with CGO_ENABLED=0.
+const HostCheckSupported = true
+
+// hostProbe adapts the internal OpenSSL prober to the public types.
+type hostProbe struct {
+	prober opensslsetup.Prober
+}
+
+func newHostProbe(library string) *hostProbe {
+	return &hostProbe{prober: opensslsetup.Prober{Library: library}}
+}
+
+func (h *hostProbe) init(ctx context.Context) error {
+	return h.prober.Init(ctx)
+}
+
+func (h *hostProbe) reprobe(ctx context.Context) error {
+	return h.prober.Reprobe(ctx)
+}
+
+func (h *hostProbe) info() HostFIPSInfo {
+	result := h.prober.Result()
+	info := HostFIPSInfo{
+		OpenSSLVersion: result.OpenSSLVersion,
+		FIPSCapable:    result.FIPSCapable,
+		Library:        result.Library,
+		Error:          result.Err,
+
+		FIPSProviderActive:    result.Providers.FIPSProviderActive,
+		DefaultPropertiesFIPS: result.Providers.DefaultPropertiesFIPS,
+		DefaultProperties:     result.Providers.DefaultProperties,
+		ConfigDir:             result.Providers.ConfigDir,
+		ConfigFile:            result.Providers.ConfigFile,
+		FIPSModuleConfig:      result.Providers.FIPSModuleConfig,
+		ModulesDir:            result.Providers.ModulesDir,
+	}
+	for _, p := range result.Providers.Providers {
+		info.Providers = append(info.Providers, ProviderInfo{
+			Name:      p.Name,
+			Version:   p.Version,
+			BuildInfo: p.BuildInfo,
+			Active:    p.Active,
+		})
+	}
+	for _, c := range result.Candidates {
+		info.Candidates = append(info.Candidates, LibraryCandidate{
+			Name:   c.Name,
+			Exists: c.Exists,
+			FIPS:   c.FIPS,
+		})
+	}
+	return info
+}
+
+func runSelfTests(ctx context.Context) (SelfTestReport, error) {
+	report, err := selftest.Run(ctx)
+	result := SelfTestReport{
+		Library:          report.Library,
+		FIPSMode:         report.FIPSMode,
+		FIPSModeError:    report.FIPSModeErr,
+		FIPSRestoreError: report.FIPSRestoreErr,
+	}
+	for _, r := range report.Results {
+		result.Results = append(result.Results, SelfTestResult{
+			Name:     r.Name,
+			Approved: r.Approved,
+			Passed:   r.Passed,
+			Duration: r.Duration,
+			Error:    r.Err,
+		})
+	}
+	return result, err
+}
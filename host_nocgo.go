@@ -0,0 +1,32 @@
+//go:build !cgo
+
+package fipscheck
+
+import "context"
+
+// HostCheckSupported reports whether this build can check the host's
+// OpenSSL FIPS capabilities. It is false in builds with CGO_ENABLED=0.
+const HostCheckSupported = false
+
+// hostProbe reports ErrHostCheckUnavailable, as OpenSSL cannot be loaded without cgo.
+type hostProbe struct{}
+
+func newHostProbe(string) *hostProbe {
+	return &hostProbe{}
+}
+
+func (*hostProbe) init(context.Context) error {
+	return ErrHostCheckUnavailable
+}
+
+func (*hostProbe) reprobe(context.Context) error {
+	return ErrHostCheckUnavailable
+}
+
+func (*hostProbe) info() HostFIPSInfo {
+	return HostFIPSInfo{Error: ErrHostCheckUnavailable}
+}
+
+func runSelfTests(context.Context) (SelfTestReport, error) {
+	return SelfTestReport{}, ErrHostCheckUnavailable
+}
@@ -0,0 +1,77 @@
+package binarychecker
+
+import (
+	"debug/elf"
+	"encoding/binary"
+	"runtime"
+	"slices"
+)
+
+// elfArchitecture maps the ELF header of a binary to the corresponding GOARCH name.
+// It returns an empty string for machines Go does not support.
+func elfArchitecture(f *elf.File) string {
+	littleEndian := f.ByteOrder == binary.LittleEndian
+	is64 := f.Class == elf.ELFCLASS64
+
+	switch f.Machine {
+	case elf.EM_X86_64:
+		return "amd64"
+	case elf.EM_386:
+		return "386"
+	case elf.EM_AARCH64:
+		return "arm64"
+	case elf.EM_ARM:
+		return "arm"
+	case elf.EM_PPC64:
+		if littleEndian {
+			return "ppc64le"
+		}
+		return "ppc64"
+	case elf.EM_S390:
+		return "s390x"
+	case elf.EM_RISCV:
+		if is64 {
+			return "riscv64"
+		}
+	case elf.EM_LOONGARCH:
+		return "loong64"
+	case elf.EM_MIPS:
+		switch {
+		case is64 && littleEndian:
+			return "mips64le"
+		case is64:
+			return "mips64"
+		case littleEndian:
+			return "mipsle"
+		default:
+			return "mips"
+		}
+	}
+	return ""
+}
+
+// hostArchitecture is the GOARCH of the running checker.
+// It is a variable to allow overriding in tests.
+var hostArchitecture = runtime.GOARCH
+
+// compatArchitectures lists, by host GOARCH, the other architectures whose
+// binaries the host runs natively. 386 binaries run on amd64 through the
+// kernel's IA-32 emulation. arm binaries are not listed for arm64, as many
+// arm64 CPUs, e.g. most server ones, cannot execute 32-bit code at all.
+var compatArchitectures = map[string][]string{
+	"amd64": {"386"},
+}
+
+// isForeignArchitecture reports whether a binary built for arch cannot be
+// executed natively on this host. Unknown architectures are treated as foreign.
+// A host that lacks the compatibility support for an architecture fails to
+// execute its binaries with ENOEXEC, which is reported as ErrForeignArch.
+func isForeignArchitecture(arch string) bool {
+	return arch != hostArchitecture && !slices.Contains(compatArchitectures[hostArchitecture], arch)
+}
+
+// NativeArchitecture reports whether binaries built for arch run natively on
+// this host, and are therefore probed at runtime rather than checked statically.
+func NativeArchitecture(arch string) bool {
+	return !isForeignArchitecture(arch)
+}
@@ -5,181 +5,173 @@ import (
 	"context"
 	"debug/buildinfo"
 	"debug/elf"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"os/exec"
-	"path/filepath"
+	"slices"
 	"strings"
-	"sync"
+	"syscall"
 	"time"
+
+	"github.com/bahe-msft/fips-check/report"
 )
 
-type GoBinaryReportDetails struct {
-	GoVersion        string
-	Module           string
-	UseSystemcrypto  bool
-	CGOEnabled       bool
-	FailsOnFIPSCheck bool   // Indicates if the binary fails when run with GOFIPS=1
-	RuntimePanicLog  string // Captures the panic log from runtime FIPS check
-}
+// GoBinaryReportDetails contains detailed information about a Go binary's FIPS capabilities.
+type GoBinaryReportDetails = report.GoBinaryReportDetails
 
 // BinaryReport contains the FIPS compliance information for a binary file.
-type BinaryReport struct {
-	// RelativePath is the path of the binary relative to the scan root
-	RelativePath string
-	// Type indicates the type of binary (e.g., "gobinary")
-	Type            string
-	GoBinaryDetails GoBinaryReportDetails
-	// Error contains any error that occurred while scanning this binary
-	Error error
-}
+type BinaryReport = report.BinaryReport
 
 // Check recursively scans the filesystem starting from the given path
 // and checks all binaries for FIPS compliance in parallel.
 // It returns a slice of BinaryReport containing the results for each binary found.
 func Check(ctx context.Context, path string) ([]BinaryReport, error) {
-	// Get absolute path for the root to calculate relative paths
-	absRoot, err := filepath.Abs(path)
+	return CheckWithOptions(ctx, path, Options{})
+}
+
+// CheckWithOptions is like Check but allows configuring the scan.
+// Reports are returned in the order the binaries were found.
+func CheckWithOptions(ctx context.Context, path string, opts Options) ([]BinaryReport, error) {
+	scan, err := Scan(ctx, path, opts)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get absolute path: %w", err)
+		return nil, err
 	}
+	return scan.Binaries, nil
+}
 
-	// Collect all binary paths first
-	var binaryPaths []string
-	err = filepath.WalkDir(absRoot, func(filePath string, d fs.DirEntry, err error) error {
-		if err != nil {
-			// Skip directories/files we can't read
-			return nil
-		}
-
-		// Check context cancellation
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
-		}
-
-		// Skip directories
-		if d.IsDir() {
-			return nil
-		}
-
-		// Skip excluded paths (e.g., /proc, /sys)
-		if shouldExcludePath(filePath) {
-			return nil
-		}
-
-		// Check if the file is a binary
-		if isBinary(filePath) {
-			binaryPaths = append(binaryPaths, filePath)
-		}
-
-		return nil
-	})
-
+// CheckFS is like CheckWithOptions but scans the tree of fsys, such as a tar
+// or zip archive or an in-memory filesystem, without extracting it to disk.
+// The files of fsys are not backed by executable OS files, so the runtime
+// probe is always skipped and every report has StaticOnly set.
+func CheckFS(ctx context.Context, fsys fs.FS, opts Options) ([]BinaryReport, error) {
+	scan, err := ScanFS(ctx, fsys, opts)
 	if err != nil {
-		return nil, fmt.Errorf("error walking directory tree: %w", err)
+		return nil, err
 	}
+	return scan.Binaries, nil
+}
 
-	// Process binaries in parallel
-	reports := make([]BinaryReport, len(binaryPaths))
-	var wg sync.WaitGroup
-	var mu sync.Mutex
-
-	// Use a semaphore to limit concurrency
-	semaphore := make(chan struct{}, 10) // Limit to 10 concurrent checks
-
-	for i, filePath := range binaryPaths {
-		// Check context cancellation before starting each goroutine
-		select {
-		case <-ctx.Done():
-			return nil, ctx.Err()
-		default:
-		}
-
-		wg.Add(1)
-		go func(idx int, fp string) {
-			defer wg.Done()
-
-			// Acquire semaphore
-			semaphore <- struct{}{}
-			defer func() { <-semaphore }()
-
-			// Check context cancellation
-			select {
-			case <-ctx.Done():
-				return
-			default:
-			}
-
-			// Calculate relative path
-			relPath, err := filepath.Rel(absRoot, fp)
-			if err != nil {
-				relPath = fp // fallback to absolute path
-			}
-
-			report := BinaryReport{
-				RelativePath: relPath,
-				Type:         "gobinary",
-			}
-
-			// Perform FIPS check
-			details, checkErr := checkGoBinaryFIPS(ctx, fp)
-			report.GoBinaryDetails = details
-			report.Error = checkErr
-
-			mu.Lock()
-			reports[idx] = report
-			mu.Unlock()
-		}(i, filePath)
+// Scan is like CheckWithOptions but returns the whole scan result, including
+// the diagnostics of the paths that could not be scanned. With Options.Strict,
+// an incomplete scan fails with report.ErrIncompleteScan and the result is
+// returned along with the error.
+func Scan(ctx context.Context, path string, opts Options) (report.Scan, error) {
+	src, err := osSource(path)
+	if err != nil {
+		return report.Scan{}, err
 	}
+	return scanSource(ctx, path, src, opts)
+}
 
-	wg.Wait()
+// ScanFS is like Scan but scans the tree of fsys, like CheckFS.
+func ScanFS(ctx context.Context, fsys fs.FS, opts Options) (report.Scan, error) {
+	return scanSource(ctx, ".", source{fsys: fsys, root: "."}, opts)
+}
 
-	// Check if context was cancelled during execution
-	if ctx.Err() != nil {
-		return nil, ctx.Err()
+// scanSource scans src and gathers the reports in walk order.
+func scanSource(ctx context.Context, root string, src source, opts Options) (report.Scan, error) {
+	var diagnostics report.ScanDiagnostics
+	reports, err := collect(ctx, src, opts, &diagnostics)
+	if err != nil {
+		return report.Scan{}, err
 	}
+	setAliases(reports)
+	scan := report.NewScan(root, reports, diagnostics)
+	if opts.Strict {
+		return scan, diagnostics.Err()
+	}
+	return scan, nil
+}
 
-	return reports, nil
+// elfMagic starts every ELF file.
+var elfMagic = []byte("\x7fELF")
+
+// binaryFile is a Go binary found by the walk. It is opened once, and its
+// static analysis is reused by every later step of its check.
+type binaryFile struct {
+	r    io.ReaderAt
+	info fs.FileInfo
+	// details holds the static analysis of the binary
+	details GoBinaryReportDetails
+	close   func() error
 }
 
-// isBinary checks if a file is an executable Go binary.
-// It checks for executable permissions, verifies it's an ELF binary,
-// and uses debug/buildinfo to confirm it's a Go binary.
-func isBinary(filePath string) bool {
+// openBinary opens the file name in fsys if it is an executable Go binary,
+// and returns nil otherwise. The cheap checks come first: the mode and size,
+// then the ELF magic, so that only ELF files are parsed for their build info.
+// The caller must close the returned file.
+// An error is returned if the file cannot be read, except if it does not
+// exist, e.g. because it is a dangling symlink.
+func openBinary(fsys fs.FS, name string, maxSize int64) (*binaryFile, error) {
 	// Check file permissions
-	info, err := os.Stat(filePath)
+	info, err := fs.Stat(fsys, name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
 	if err != nil {
-		return false
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, nil
+	}
+	if maxSize > 0 && info.Size() > maxSize {
+		return nil, nil
 	}
 
 	// Check if file has executable permission
 	if info.Mode()&0111 == 0 {
-		return false
+		return nil, nil
 	}
 
-	// Try to open as ELF file to verify it's a binary
-	f, err := elf.Open(filePath)
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	magic := make([]byte, len(elfMagic))
+	if _, err := io.ReadFull(f, magic); err != nil || !bytes.Equal(magic, elfMagic) {
+		f.Close()
+		if err == nil || err == io.EOF || err == io.ErrUnexpectedEOF {
+			// Too short or not an ELF file
+			return nil, nil
+		}
+		return nil, err
+	}
+	r, closeFile, err := readerAt(f, magic)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	f.Close()
 
-	_, err = buildinfo.ReadFile(filePath)
+	details, err := analyzeStatic(io.NewSectionReader(r, 0, info.Size()))
 	if err != nil {
 		// Not a Go binary
 		// TODO: handle special cases where the binary is built by bazel
-		return false
+		closeFile()
+		return nil, nil
 	}
+	return &binaryFile{r: r, info: info, details: details, close: closeFile}, nil
+}
 
-	return true
+// readerAt returns random access to f, of which head has already been read,
+// and closes f. Files that do not implement io.ReaderAt, such as tar entries,
+// are read into memory.
+func readerAt(f fs.File, head []byte) (io.ReaderAt, func() error, error) {
+	if r, ok := f.(io.ReaderAt); ok {
+		return r, f.Close, nil
+	}
+	defer f.Close()
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		return nil, nil, err
+	}
+	return bytes.NewReader(append(head, rest...)), func() error { return nil }, nil
 }
 
 // shouldExcludePath checks if a path should be excluded from scanning.
 // This excludes virtual filesystems like /proc and /sys that contain symlinks
-// to running processes.
+// to running processes. Running processes are checked by the procscan package.
 func shouldExcludePath(filePath string) bool {
 	// List of path prefixes to exclude
 	excludedPrefixes := []string{
@@ -197,23 +189,44 @@ func shouldExcludePath(filePath string) bool {
 	return false
 }
 
-// checkGoBinaryFIPS performs FIPS compliance check on a Go binary.
-// It extracts build information and determines FIPS capability.
-// Returns: details GoBinaryReportDetails, error
-func checkGoBinaryFIPS(ctx context.Context, filePath string) (GoBinaryReportDetails, error) {
+// Analyze performs the static analysis of a single Go binary read from r,
+// which holds size bytes. The binary is never executed, so the returned
+// details have StaticOnly set.
+func Analyze(ctx context.Context, r io.ReaderAt, size int64) (GoBinaryReportDetails, error) {
+	if err := ctx.Err(); err != nil {
+		return GoBinaryReportDetails{}, err
+	}
+	details, err := analyzeStatic(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return details, err
+	}
+	return staticOnly(details), nil
+}
+
+// staticOnly marks the static analysis of a binary as its final result.
+func staticOnly(details GoBinaryReportDetails) GoBinaryReportDetails {
+	details.RuntimeOutcome = RuntimeOutcomeSkipped
+	details.ExitCode = -1
+	details.StaticOnly = true
+	return details
+}
+
+// analyzeStatic reads the architecture and build settings of a Go binary.
+func analyzeStatic(r io.ReaderAt) (GoBinaryReportDetails, error) {
 	details := GoBinaryReportDetails{}
 
-	// Check context cancellation
-	select {
-	case <-ctx.Done():
-		return details, ctx.Err()
-	default:
+	// Determine the target architecture from the ELF header
+	f, err := elf.NewFile(r)
+	if err != nil {
+		return details, fmt.Errorf("%w: %w", report.ErrNotELF, err)
 	}
+	details.Architecture = elfArchitecture(f)
+	details.ForeignArchitecture = isForeignArchitecture(details.Architecture)
 
 	// Read build info from the binary
-	info, err := buildinfo.ReadFile(filePath)
+	info, err := buildinfo.Read(r)
 	if err != nil {
-		return details, fmt.Errorf("failed to read build info: %w", err)
+		return details, fmt.Errorf("%w: %w", report.ErrNoBuildInfo, err)
 	}
 
 	// Extract Go version
@@ -236,86 +249,185 @@ func checkGoBinaryFIPS(ctx context.Context, filePath string) (GoBinaryReportDeta
 			}
 		}
 	}
+	return details, nil
+}
+
+// checkGoBinaryFIPS completes the FIPS compliance check of the Go binary at
+// filePath, whose static analysis is details, with the runtime FIPS check.
+// Returns: details GoBinaryReportDetails, error
+func checkGoBinaryFIPS(ctx context.Context, filePath string, details GoBinaryReportDetails, opts Options) (GoBinaryReportDetails, error) {
+	// Check context cancellation
+	select {
+	case <-ctx.Done():
+		return details, ctx.Err()
+	default:
+	}
 
-	passed, panicLog, err := checkRuntimeFIPS(ctx, filePath)
+	if details.ForeignArchitecture || opts.DisableRuntimeProbe {
+		// The binary cannot or must not be executed, so only the static verdict applies
+		return staticOnly(details), nil
+	}
+
+	result, err := checkRuntimeFIPS(ctx, filePath, opts)
 	if err != nil {
 		// If we can't perform runtime check, return the static analysis result
 		return details, fmt.Errorf("runtime FIPS check failed: %w", err)
 	}
+	details.RuntimeOutcome = result.Outcome
+	details.PanicMessage = result.PanicMessage
+	details.ExitCode = result.ExitCode
+	details.Signal = result.Signal
+	details.RuntimeStdout = result.Stdout
 	// Store the panic log in details
-	details.RuntimePanicLog = panicLog
-	// Set FailsOnFIPSCheck to true if the binary did not pass (failed)
-	details.FailsOnFIPSCheck = !passed
+	details.RuntimePanicLog = result.Stderr
+	details.FailsOnFIPSCheck = result.Outcome.FailsFIPS()
+
+	if !result.Outcome.Conclusive() {
+		details.StaticOnly = true
+		execErr := &report.ExecError{Path: filePath, ExitCode: result.ExitCode, Signal: result.Signal}
+		if result.Outcome == RuntimeOutcomeWrongArchitecture {
+			execErr.Err = report.ErrForeignArch
+		}
+		return details, fmt.Errorf("runtime FIPS check inconclusive: %w", execErr)
+	}
 
 	return details, nil
 }
 
+// probeError returns the error implied by the final details of a binary whose
+// runtime check, although enabled, did not fully run: the binary was built for
+// an architecture this host cannot execute, or was still running when the
+// check timed out. As it follows from details, it is restored along with
+// analyses found in the cache.
+func (o Options) probeError(details GoBinaryReportDetails) error {
+	switch {
+	case o.DisableRuntimeProbe:
+		return nil
+	case details.ForeignArchitecture:
+		return fmt.Errorf("runtime FIPS check skipped: %w: %s", report.ErrForeignArch, details.Architecture)
+	case details.RuntimeOutcome == RuntimeOutcomeTimeout:
+		return fmt.Errorf("runtime FIPS check incomplete: %w: binary still running after %s",
+			report.ErrRuntimeProbeTimeout, o.runtimeTimeout())
+	}
+	return nil
+}
+
+// maxCapturedOutput limits how much stdout and stderr is kept from a runtime check.
+const maxCapturedOutput = 64 * 1024
+
+// cappedBuffer is an io.Writer that keeps at most maxCapturedOutput bytes
+// and silently discards the rest, so chatty binaries cannot exhaust memory.
+type cappedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := maxCapturedOutput - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// runtimeResult is the outcome of executing a binary with GOFIPS=1.
+type runtimeResult struct {
+	Outcome      RuntimeOutcome
+	PanicMessage string
+	ExitCode     int
+	Signal       string
+	Stdout       string
+	Stderr       string
+}
+
 // checkRuntimeFIPS attempts to run the binary with GOFIPS=1 environment variable
 // to verify runtime FIPS compliance.
 //
 // Requirements:
 //   - The binary is invoked with environment variable GOFIPS=1 to enforce FIPS mode
-//   - If the binary panics with an error message like:
+//   - The binary is given a short timeout (2 seconds by default) to start and potentially panic
+//   - The dynamic loader output, the Go panic message and the first goroutine trace
+//     are parsed to classify the outcome, for example:
 //     "panic: opensslcrypto: FIPS mode requested (system FIPS mode) but not available in OpenSSL 3.0.16"
-//     then it is NOT FIPS compliant (returns false)
-//   - If the binary does not panic with FIPS-related errors, it MIGHT BE FIPS compliant
-//     (returns true), as actual compliance depends on the host system configuration
-//   - The binary is given a short timeout (2 seconds) to start and potentially panic
+//     is classified as RuntimeOutcomeFIPSPanic
+//   - Without a recognizable crash, the outcome is derived from the exit status:
+//     clean exit, non-zero exit, signal or timeout. These MIGHT BE FIPS compliant,
+//     as actual compliance depends on the host system configuration
 //
-// Returns:
-// - bool: true if binary might be FIPS compliant, false if FIPS panic detected
-// - string: the panic log or stderr output captured during execution
-// - error: if the check cannot be performed
-func checkRuntimeFIPS(ctx context.Context, filePath string) (bool, string, error) {
+// An error is returned only if the check itself cannot be performed, as a
+// *report.ExecError unless ctx is done; a binary that cannot be executed on
+// this host is reported as RuntimeOutcomeWrongArchitecture.
+func checkRuntimeFIPS(ctx context.Context, filePath string, opts Options) (runtimeResult, error) {
 	// Create a context with timeout for the binary execution
-	execCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	execCtx, cancel := context.WithTimeout(ctx, opts.runtimeTimeout())
 	defer cancel()
 
 	// Prepare the command with GOFIPS=1 environment variable
 	cmd := exec.CommandContext(execCtx, filePath)
-	cmd.Env = append(os.Environ(), "GOFIPS=1")
+	env := opts.Env
+	if env == nil {
+		env = os.Environ()
+	}
+	cmd.Env = append(slices.Clip(env), "GOFIPS=1")
+	// Don't wait forever for output pipes held open by child processes
+	cmd.WaitDelay = time.Second
 
-	// Capture stderr to check for FIPS-related panic messages
-	var stderr bytes.Buffer
+	// Capture both streams; stderr carries loader errors and panics
+	var stdout, stderr cappedBuffer
+	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	// Run the command
-	err := cmd.Run()
-
-	// Check the stderr output for FIPS-related panic messages
-	stderrOutput := stderr.String()
+	runErr := cmd.Run()
 
-	// Look for FIPS mode panic indicators
-	fipsPanicIndicators := []string{
-		"panic: opensslcrypto: FIPS mode requested",
-		"FIPS mode requested",
-		"but not available in OpenSSL",
+	result := runtimeResult{
+		ExitCode: -1,
+		Stdout:   stdout.String(),
+		Stderr:   stderr.String(),
 	}
 
-	for _, indicator := range fipsPanicIndicators {
-		if strings.Contains(stderrOutput, indicator) {
-			// Binary panicked due to FIPS unavailability - NOT FIPS compliant
-			// Return the panic log
-			return false, stderrOutput, nil
+	if cmd.ProcessState == nil {
+		// The process never started
+		if errors.Is(runErr, syscall.ENOEXEC) {
+			result.Outcome = RuntimeOutcomeWrongArchitecture
+			return result, nil
 		}
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if execCtx.Err() != nil {
+			runErr = report.ErrRuntimeProbeTimeout
+		}
+		return result, &report.ExecError{Path: filePath, ExitCode: -1, Err: runErr}
 	}
 
-	// If the command timed out or exited for other reasons without FIPS panic,
-	// we consider it potentially FIPS compliant
-	if err != nil {
-		// Check if it's a timeout or context cancellation
-		if execCtx.Err() == context.DeadlineExceeded {
-			// Timeout means the binary ran without panicking immediately
-			// This is a good sign for FIPS compliance
-			return true, stderrOutput, nil
-		}
+	result.ExitCode = cmd.ProcessState.ExitCode()
+	if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+		result.Signal = status.Signal().String()
+	}
 
-		// For other errors, if there's no FIPS panic in stderr, still consider it compliant
-		if !strings.Contains(stderrOutput, "FIPS") {
-			return true, stderrOutput, nil
-		}
+	if outcome, info, ok := classifyStderr(result.Stderr); ok {
+		result.Outcome = outcome
+		result.PanicMessage = info.Message
+		return result, nil
+	}
+
+	switch {
+	case execCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil:
+		// Timeout means the binary ran without panicking immediately
+		// This is a good sign for FIPS compliance
+		result.Outcome = RuntimeOutcomeTimeout
+		result.Signal = ""
+	case ctx.Err() != nil:
+		return result, ctx.Err()
+	case result.Signal != "":
+		result.Outcome = RuntimeOutcomeSignal
+	case result.ExitCode == 0:
+		result.Outcome = RuntimeOutcomeCleanExit
+	default:
+		result.Outcome = RuntimeOutcomeNonZeroExit
 	}
 
-	// No FIPS-related panic detected - might be FIPS compliant
-	return true, stderrOutput, nil
+	return result, nil
 }
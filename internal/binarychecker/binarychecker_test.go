@@ -0,0 +1,105 @@
+package binarychecker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+func TestOpenBinary(t *testing.T) {
+	goBinary := testutil.TestBinary(t)
+	// An ELF file without Go build info
+	elfHeader := append([]byte("\x7fELF\x02\x01\x01"), make([]byte, 57)...)
+
+	fsys := fstest.MapFS{
+		"go":         {Data: goBinary, Mode: 0755},
+		"script":     {Data: []byte("#!/bin/sh\necho hi\n"), Mode: 0755},
+		"short":      {Data: []byte("\x7fE"), Mode: 0755},
+		"empty":      {Mode: 0755},
+		"elf":        {Data: elfHeader, Mode: 0755},
+		"not-exec":   {Data: goBinary, Mode: 0644},
+		"dir":        {Mode: os.ModeDir | 0755},
+		"go-too-big": {Data: goBinary, Mode: 0755},
+	}
+	tests := []struct {
+		name    string
+		maxSize int64
+		want    bool
+	}{
+		{name: "go", want: true},
+		{name: "script"},
+		{name: "short"},
+		{name: "empty"},
+		{name: "elf"},
+		{name: "not-exec"},
+		{name: "dir"},
+		{name: "missing"},
+		{name: "go-too-big", maxSize: 1024},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file, err := openBinary(fsys, tt.name, tt.maxSize)
+			if err != nil {
+				t.Fatalf("openBinary failed: %v", err)
+			}
+			if (file != nil) != tt.want {
+				t.Fatalf("Expected Go binary %v, got %v", tt.want, file != nil)
+			}
+			if file == nil {
+				return
+			}
+			defer file.close()
+			if file.details.GoVersion == "" || file.details.Architecture == "" {
+				t.Errorf("Expected the static analysis to be kept, got %+v", file.details)
+			}
+			if file.info.Size() != int64(len(goBinary)) {
+				t.Errorf("Expected size %d, got %d", len(goBinary), file.info.Size())
+			}
+		})
+	}
+}
+
+func TestIsForeignArchitecture(t *testing.T) {
+	defer func(arch string) { hostArchitecture = arch }(hostArchitecture)
+
+	tests := []struct {
+		host, arch string
+		foreign    bool
+	}{
+		{"amd64", "amd64", false},
+		{"amd64", "386", false},
+		{"amd64", "arm64", true},
+		{"arm64", "arm", true},
+		{"386", "amd64", true},
+		{"amd64", "", true},
+	}
+	for _, tt := range tests {
+		hostArchitecture = tt.host
+		if foreign := isForeignArchitecture(tt.arch); foreign != tt.foreign {
+			t.Errorf("isForeignArchitecture(%q) on %s = %t, expected %t", tt.arch, tt.host, foreign, tt.foreign)
+		}
+	}
+}
+
+func TestCheckGoBinaryFIPSMissingLibrary(t *testing.T) {
+	// The dynamic loader fails before the Go runtime starts, as for a binary
+	// linked against a library the host lacks
+	script := writeScript(t, `echo "$0: error while loading shared libraries: libzstd.so.1: cannot open shared object file: No such file or directory" >&2; exit 127`)
+
+	details, err := checkGoBinaryFIPS(context.Background(), script, GoBinaryReportDetails{UseSystemcrypto: true}, Options{})
+	if details.RuntimeOutcome != RuntimeOutcomeMissingLibrary {
+		t.Errorf("Expected %s, got %s", RuntimeOutcomeMissingLibrary, details.RuntimeOutcome)
+	}
+	if !details.StaticOnly || details.FailsOnFIPSCheck {
+		t.Errorf("Expected an inconclusive static-only verdict, got %+v", details)
+	}
+	var execErr *report.ExecError
+	if !errors.As(err, &execErr) || execErr.ExitCode != 127 {
+		t.Errorf("Expected an ExecError with exit code 127, got %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package binarychecker
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"slices"
+	"sync"
+)
+
+// fileKey identifies a file by device and inode, so hardlinks share it.
+type fileKey struct {
+	dev, ino uint64
+}
+
+// dedup makes sure identical binaries, e.g. hardlinks, copies or multi-call
+// binaries installed under several names, are analyzed only once per scan.
+type dedup struct {
+	mu sync.Mutex
+	// digests caches the digest of every hashed file by inode
+	digests map[fileKey]string
+	// entries holds the analysis of every distinct binary by digest
+	entries map[string]*dedupEntry
+}
+
+// dedupEntry is the analysis of a distinct binary, shared by all its paths.
+type dedupEntry struct {
+	// path is the relative path the binary was analyzed at. For a symlink,
+	// it is the path of its target.
+	path    string
+	done    bool
+	details GoBinaryReportDetails
+	err     error
+	// cached indicates the analysis was taken from Options.Cache
+	cached bool
+	// followers are the reports of the other paths that wait for the analysis
+	followers []result
+}
+
+func newDedup() *dedup {
+	return &dedup{
+		digests: make(map[fileKey]string),
+		entries: make(map[string]*dedupEntry),
+	}
+}
+
+func newDedupEntry(path string) *dedupEntry {
+	return &dedupEntry{path: path}
+}
+
+// digest returns the SHA-256 digest of f, as "sha256:<hex>". Hardlinks of a
+// file that was already hashed are not read again.
+func (d *dedup) digest(f *binaryFile) (string, error) {
+	key, hasKey := inodeKey(f.info)
+	if hasKey {
+		d.mu.Lock()
+		digest, ok := d.digests[key]
+		d.mu.Unlock()
+		if ok {
+			return digest, nil
+		}
+	}
+
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(f.r, 0, f.info.Size())); err != nil {
+		return "", err
+	}
+	digest := "sha256:" + hex.EncodeToString(h.Sum(nil))
+
+	if hasKey {
+		d.mu.Lock()
+		d.digests[key] = digest
+		d.mu.Unlock()
+	}
+	return digest, nil
+}
+
+// claim returns the entry of the binary with digest. If the caller is the first
+// to claim it, owner is true and the caller must analyze the binary at path
+// and publish the result; other callers follow it.
+func (d *dedup) claim(digest, path string) (entry *dedupEntry, owner bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if entry, ok := d.entries[digest]; ok {
+		return entry, false
+	}
+	entry = newDedupEntry(path)
+	d.entries[digest] = entry
+	return entry, true
+}
+
+// follow queues r until the analysis of e is published, so that no worker
+// blocks while waiting for it. It returns false if the analysis is already
+// published, in which case the caller completes r itself.
+func (d *dedup) follow(e *dedupEntry, r result) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if e.done {
+		return false
+	}
+	e.followers = append(e.followers, r)
+	return true
+}
+
+// publish records the analysis of e and returns the reports that follow it.
+func (d *dedup) publish(e *dedupEntry, details GoBinaryReportDetails, err error) []result {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	e.details, e.err, e.done = details, err, true
+	followers := e.followers
+	e.followers = nil
+	return followers
+}
+
+// report completes r with the analysis of e. Reports of other paths than the
+// one the binary was analyzed at name that path as their alias.
+func (e *dedupEntry) report(r result) result {
+	r.report.GoBinaryDetails, r.report.Error = e.details, e.err
+	r.report.Cached = e.cached
+	if r.report.RelativePath != e.path {
+		r.report.AliasOf = e.path
+	}
+	return r
+}
+
+// setAliases lists, in each report, the other paths with the same digest.
+func setAliases(reports []BinaryReport) {
+	paths := make(map[string][]string)
+	for _, r := range reports {
+		if r.Digest != "" {
+			paths[r.Digest] = append(paths[r.Digest], r.RelativePath)
+		}
+	}
+	for i, r := range reports {
+		if same := paths[r.Digest]; len(same) > 1 {
+			reports[i].Aliases = slices.DeleteFunc(slices.Clone(same), func(p string) bool {
+				return p == r.RelativePath
+			})
+		}
+	}
+}
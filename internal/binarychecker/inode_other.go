@@ -0,0 +1,10 @@
+//go:build !unix
+
+package binarychecker
+
+import "io/fs"
+
+// inodeKey returns false, as inodes are only available on Unix.
+func inodeKey(info fs.FileInfo) (fileKey, bool) {
+	return fileKey{}, false
+}
@@ -0,0 +1,17 @@
+//go:build unix
+
+package binarychecker
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// inodeKey returns the device and inode of a file, if info comes from the OS.
+func inodeKey(info fs.FileInfo) (fileKey, bool) {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return fileKey{}, false
+	}
+	return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}, true
+}
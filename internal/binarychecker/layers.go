@@ -0,0 +1,255 @@
+package binarychecker
+
+import (
+	"archive/tar"
+	"context"
+	"io/fs"
+	"maps"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/cache"
+	"github.com/bahe-msft/fips-check/internal/ociimage"
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// LayeredScan scans a root filesystem that is built from layers, such as a
+// container image, one layer at a time: right after a layer is applied, only
+// the files it wrote are checked. The reports of each layer are stored in
+// Options.Cache by layer digest, so that layers shared by many images, e.g.
+// their base image, are scanned only once.
+type LayeredScan struct {
+	root string
+	opts Options
+	// binaries holds the reports of the Go binaries in the root filesystem by path
+	binaries map[string]BinaryReport
+	// symlinks holds the paths of the symlinks in the root filesystem
+	symlinks map[string]bool
+}
+
+// NewLayeredScan returns a scan of the root filesystem in the directory root,
+// to which no layer has been applied yet.
+func NewLayeredScan(root string, opts Options) *LayeredScan {
+	return &LayeredScan{
+		root:     root,
+		opts:     opts,
+		binaries: make(map[string]BinaryReport),
+		symlinks: make(map[string]bool),
+	}
+}
+
+// AddLayer updates the scan with a layer that has just been applied to the
+// root filesystem: reports of files the layer removed or replaced are
+// dropped, and the Go binaries it wrote are checked, unless the layer with
+// digest is found in Options.Cache.
+func (s *LayeredScan) AddLayer(ctx context.Context, digest string, changes ociimage.LayerChanges) error {
+	if err := s.opts.validate(); err != nil {
+		return err
+	}
+	var key cache.Key
+	if s.opts.Cache != nil && digest != "" {
+		key = s.opts.layerCacheKey(digest)
+		if reports, ok := s.opts.Cache.GetLayer(key); ok {
+			s.apply(changes, reports, true)
+			return nil
+		}
+	}
+
+	reports, complete, err := s.scanLayer(ctx, changes)
+	if err != nil {
+		return err
+	}
+	// Only layers whose every file could be checked are stored, as errors are often transient
+	if key.Digest != "" && complete {
+		if err := s.opts.Cache.PutLayer(key, reports); err != nil {
+			s.opts.logger().Debug("cannot store layer in cache", "layer", digest, "error", err)
+		}
+	}
+	s.apply(changes, reports, false)
+	return nil
+}
+
+// scanLayer checks the regular files written by a layer. The reports name
+// the files by their path in the layer archive, which unlike the path they
+// were written to does not depend on the lower layers. complete is false if
+// any file could not be checked.
+func (s *LayeredScan) scanLayer(ctx context.Context, changes ociimage.LayerChanges) (reports []BinaryReport, complete bool, err error) {
+	names := make(map[string]string)
+	fsys := layerFS{FS: os.DirFS(s.root), files: make(map[string]bool), dirs: map[string]bool{".": true}}
+	for _, entry := range changes.Entries {
+		// A later entry of the layer replaces an earlier one at the same path
+		delete(fsys.files, entry.Path)
+		if entry.Type != tar.TypeReg {
+			continue
+		}
+		names[entry.Path] = entry.Name
+		fsys.files[entry.Path] = true
+		for dir := path.Dir(entry.Path); dir != "."; dir = path.Dir(dir) {
+			fsys.dirs[dir] = true
+		}
+	}
+	if len(fsys.files) == 0 {
+		return nil, true, nil
+	}
+
+	var diagnostics report.ScanDiagnostics
+	reports, err = collect(ctx, source{fsys: fsys, root: ".", osDir: s.root}, s.opts, &diagnostics)
+	if err != nil {
+		return nil, false, err
+	}
+	complete = diagnostics.Complete()
+	for i, r := range reports {
+		reports[i].RelativePath = names[filepath.ToSlash(r.RelativePath)]
+		complete = complete && r.Error == nil
+	}
+	if s.opts.Strict {
+		if err := diagnostics.Err(); err != nil {
+			return nil, false, err
+		}
+	}
+	return reports, complete, nil
+}
+
+// apply updates the root filesystem view with the changes of a layer and the
+// reports of the binaries it wrote, as returned by scanLayer.
+func (s *LayeredScan) apply(changes ociimage.LayerChanges, reports []BinaryReport, cached bool) {
+	// removed paths are gone with their content, cleared directories keep
+	// only themselves and replaced paths lose only themselves
+	removed := make(map[string]bool)
+	cleared := make(map[string]bool)
+	replaced := make(map[string]bool)
+	for _, p := range changes.Removed {
+		removed[p] = true
+	}
+	for _, p := range changes.Cleared {
+		cleared[p] = true
+	}
+	paths := make(map[string]string)
+	for _, entry := range changes.Entries {
+		if entry.Type == tar.TypeDir {
+			replaced[entry.Path] = true
+		} else {
+			removed[entry.Path] = true
+		}
+		paths[entry.Name] = entry.Path
+	}
+	stale := func(p string) bool {
+		if removed[p] || replaced[p] {
+			return true
+		}
+		for dir := path.Dir(p); ; dir = path.Dir(dir) {
+			if removed[dir] || cleared[dir] {
+				return true
+			}
+			if dir == "." {
+				return false
+			}
+		}
+	}
+	maps.DeleteFunc(s.binaries, func(p string, _ BinaryReport) bool { return stale(p) })
+	maps.DeleteFunc(s.symlinks, func(p string, _ bool) bool { return stale(p) })
+
+	for _, r := range reports {
+		p, ok := paths[r.RelativePath]
+		if !ok {
+			continue
+		}
+		r.RelativePath = p
+		r.Cached = r.Cached || cached
+		s.binaries[p] = r
+	}
+	for _, entry := range changes.Entries {
+		switch entry.Type {
+		case tar.TypeLink:
+			// Hardlinks share the analysis of their file, which may come from a lower layer
+			if r, ok := s.binaries[entry.Linkname]; ok && s.selected(entry.Path) {
+				r.RelativePath = entry.Path
+				s.binaries[entry.Path] = r
+			}
+		case tar.TypeSymlink:
+			s.symlinks[entry.Path] = true
+		}
+	}
+}
+
+// selected reports whether a walk of the root filesystem would check the
+// file at p, a slash-separated path, according to Include and Exclude.
+func (s *LayeredScan) selected(p string) bool {
+	for dir := path.Dir(p); dir != "."; dir = path.Dir(dir) {
+		if matchAny(s.opts.Exclude, dir) {
+			return false
+		}
+	}
+	return s.opts.included(p)
+}
+
+// Reports returns the reports of the Go binaries in the root filesystem
+// after the last layer, in walk order. Unless Options.Symlinks is
+// SymlinkSkip, symlinks to Go binaries are reported as aliases of their
+// targets, resolved inside the root filesystem like by a scan of it.
+func (s *LayeredScan) Reports() []BinaryReport {
+	reports := slices.Collect(maps.Values(s.binaries))
+	if s.opts.Symlinks == SymlinkFollow {
+		for p := range s.symlinks {
+			if !s.selected(p) {
+				continue
+			}
+			target, err := rootfs.Rel(s.root, p)
+			if err != nil {
+				continue
+			}
+			if r, ok := s.binaries[target]; ok {
+				r.RelativePath = p
+				r.SymlinkTarget = target
+				reports = append(reports, r)
+			}
+		}
+	}
+	slices.SortFunc(reports, func(a, b BinaryReport) int {
+		return slices.Compare(strings.Split(a.RelativePath, "/"), strings.Split(b.RelativePath, "/"))
+	})
+
+	// The first file of each binary in walk order is the one it was analyzed
+	// at, and symlinks are aliases of their targets
+	origins := make(map[string]string)
+	for i := range reports {
+		r := &reports[i]
+		r.AliasOf, r.Aliases = "", nil
+		if r.Digest == "" || r.SymlinkTarget != "" {
+			continue
+		}
+		if origin, ok := origins[r.Digest]; ok {
+			r.AliasOf = origin
+		} else {
+			origins[r.Digest] = r.RelativePath
+		}
+	}
+	for i := range reports {
+		if r := &reports[i]; r.SymlinkTarget != "" {
+			r.AliasOf = r.SymlinkTarget
+		}
+	}
+	setAliases(reports)
+	return reports
+}
+
+// layerFS is the view of a root filesystem restricted to the files written by
+// one layer and their parent directories.
+type layerFS struct {
+	fs.FS
+	files map[string]bool
+	dirs  map[string]bool
+}
+
+// ReadDir implements fs.ReadDirFS, hiding the entries that are not part of the layer.
+func (f layerFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := fs.ReadDir(f.FS, name)
+	return slices.DeleteFunc(entries, func(e fs.DirEntry) bool {
+		p := path.Join(name, e.Name())
+		return !f.files[p] && !f.dirs[p]
+	}), err
+}
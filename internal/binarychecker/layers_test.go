@@ -0,0 +1,96 @@
+package binarychecker
+
+import (
+	"archive/tar"
+	"context"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bahe-msft/fips-check/internal/cache"
+	"github.com/bahe-msft/fips-check/internal/ociimage"
+	"github.com/bahe-msft/fips-check/internal/testutil"
+)
+
+func TestLayeredScan(t *testing.T) {
+	opts := Options{DisableRuntimeProbe: true, Cache: cache.New(t.TempDir())}
+
+	// scan applies two layers to a fresh root, the way ociimage.UnpackLayers would
+	scan := func() []BinaryReport {
+		root := t.TempDir()
+		write := func(name string, data []byte) {
+			if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(root, name), data, 0755); err != nil {
+				t.Fatal(err)
+			}
+		}
+		layers := NewLayeredScan(root, opts)
+
+		testutil.WriteTestBinaries(t, root, "bin/app", "bin/old", "opt/tool")
+		if err := os.Symlink("/bin/app", filepath.Join(root, "app")); err != nil {
+			t.Fatal(err)
+		}
+		base := ociimage.LayerChanges{Entries: []ociimage.LayerEntry{
+			{Name: "bin/app", Path: "bin/app", Type: tar.TypeReg},
+			{Name: "bin/old", Path: "bin/old", Type: tar.TypeReg},
+			{Name: "opt/tool", Path: "opt/tool", Type: tar.TypeReg},
+			{Name: "app", Path: "app", Type: tar.TypeSymlink, Linkname: "/bin/app"},
+		}}
+		if err := layers.AddLayer(context.Background(), "sha256:base", base); err != nil {
+			t.Fatalf("AddLayer failed: %v", err)
+		}
+
+		if err := os.Remove(filepath.Join(root, "bin/old")); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.RemoveAll(filepath.Join(root, "opt")); err != nil {
+			t.Fatal(err)
+		}
+		write("opt/notes", []byte("not a binary"))
+		if err := os.Link(filepath.Join(root, "bin/app"), filepath.Join(root, "bin/app2")); err != nil {
+			t.Fatal(err)
+		}
+		upper := ociimage.LayerChanges{
+			Removed: []string{"bin/old"},
+			Cleared: []string{"opt"},
+			Entries: []ociimage.LayerEntry{
+				{Name: "opt/notes", Path: "opt/notes", Type: tar.TypeReg},
+				{Name: "bin/app2", Path: "bin/app2", Type: tar.TypeLink, Linkname: "bin/app"},
+			},
+		}
+		if err := layers.AddLayer(context.Background(), "sha256:upper", upper); err != nil {
+			t.Fatalf("AddLayer failed: %v", err)
+		}
+		return layers.Reports()
+	}
+
+	for i, cached := range []bool{false, true} {
+		reports := scan()
+		var paths []string
+		for _, r := range reports {
+			paths = append(paths, r.RelativePath)
+			if r.Cached != cached {
+				t.Errorf("Scan %d: expected Cached=%v for %s", i+1, cached, r.RelativePath)
+			}
+			if r.Digest == "" || r.Digest != reports[0].Digest {
+				t.Errorf("Scan %d: expected the same digest for %s, got %q", i+1, r.RelativePath, r.Digest)
+			}
+		}
+		// The removed and cleared files are gone, the symlink resolves inside the root
+		expected := []string{"app", "bin/app", "bin/app2"}
+		if !slices.Equal(paths, expected) {
+			t.Fatalf("Scan %d: expected reports for %v, got %v", i+1, expected, paths)
+		}
+		// bin/app was analyzed, the symlink app and the hardlink bin/app2 reuse it
+		if reports[0].AliasOf != "bin/app" || reports[0].SymlinkTarget != "bin/app" ||
+			reports[1].AliasOf != "" || reports[2].AliasOf != "bin/app" {
+			t.Errorf("Scan %d: expected the other paths to be aliases of bin/app, got %+v", i+1, reports)
+		}
+		if len(reports[0].Aliases) != 2 {
+			t.Errorf("Scan %d: expected 2 aliases, got %v", i+1, reports[0].Aliases)
+		}
+	}
+}
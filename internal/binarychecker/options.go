@@ -0,0 +1,187 @@
+package binarychecker
+
+import (
+	"fmt"
+	"log/slog"
+	"path"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/cache"
+)
+
+// defaultConcurrency is the number of runtime FIPS checks run at once unless
+// Options.Concurrency is set.
+const defaultConcurrency = 10
+
+// defaultRuntimeTimeout bounds a runtime FIPS check unless Options.RuntimeTimeout is set.
+const defaultRuntimeTimeout = 2 * time.Second
+
+// SymlinkPolicy controls how symlinks found during a walk are handled.
+type SymlinkPolicy int
+
+const (
+	// SymlinkFollow reports symlinks to Go binaries as aliases of their
+	// targets. Symlinks are resolved inside the scan root, so that absolute
+	// targets in a mounted root filesystem never point at the host.
+	SymlinkFollow SymlinkPolicy = iota
+	// SymlinkSkip ignores symlinks; only their targets are checked, if found.
+	SymlinkSkip
+)
+
+// Analyzer identifies a kind of binary analysis. The analyzer that produced
+// a report is recorded in BinaryReport.Type.
+type Analyzer string
+
+// AnalyzerGoBinary analyzes Go binaries.
+const AnalyzerGoBinary Analyzer = "gobinary"
+
+// analyzers lists the supported analyzers.
+var analyzers = []Analyzer{AnalyzerGoBinary}
+
+// Options configures a scan. The zero value performs a full scan,
+// including the runtime FIPS check.
+type Options struct {
+	// DisableRuntimeProbe skips executing binaries with GOFIPS=1, so that
+	// every verdict is based on static analysis only.
+	DisableRuntimeProbe bool
+	// Progress, if set, is called whenever the scan makes progress.
+	// Calls are serialized but may come from different goroutines.
+	Progress func(Progress)
+	// Concurrency is the number of binaries executed at once for the
+	// runtime FIPS check, 10 if zero.
+	Concurrency int
+	// AnalysisConcurrency is the number of files parsed and hashed at once,
+	// runtime.GOMAXPROCS if zero.
+	AnalysisConcurrency int
+	// RuntimeTimeout bounds each runtime FIPS check, 2 seconds if zero.
+	RuntimeTimeout time.Duration
+	// Include, if set, restricts the scan to files matching one of the patterns.
+	// Exclude skips matching files and directories. Patterns use path.Match
+	// syntax; patterns containing a slash match the slash-separated path
+	// relative to the scan root, others match the base name.
+	Include []string
+	Exclude []string
+	// Symlinks controls how symlinks are handled.
+	Symlinks SymlinkPolicy
+	// MaxFileSize skips files larger than this many bytes, if positive.
+	MaxFileSize int64
+	// Analyzers selects the analyzers to run, all of them if empty.
+	Analyzers []Analyzer
+	// Env, if not nil, replaces the environment the runtime FIPS check
+	// inherits from the current process. GOFIPS=1 is always added.
+	Env []string
+	// Logger, if set, receives debug logs about skipped files and checks.
+	Logger *slog.Logger
+	// Cache, if set, stores the results of successful analyses across scans.
+	Cache *cache.Cache
+	// HostFingerprint identifies the host's OpenSSL in the keys of runtime
+	// probe results in Cache, see cache.Fingerprint.
+	HostFingerprint string
+	// Strict fails the scan with report.ErrIncompleteScan if any path could
+	// not be scanned, instead of only recording it in the diagnostics.
+	Strict bool
+}
+
+// validate reports invalid options before a scan starts.
+func (o Options) validate() error {
+	if o.Concurrency < 0 {
+		return fmt.Errorf("invalid concurrency %d", o.Concurrency)
+	}
+	if o.AnalysisConcurrency < 0 {
+		return fmt.Errorf("invalid analysis concurrency %d", o.AnalysisConcurrency)
+	}
+	if o.RuntimeTimeout < 0 {
+		return fmt.Errorf("invalid runtime timeout %s", o.RuntimeTimeout)
+	}
+	for _, pattern := range slices.Concat(o.Include, o.Exclude) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+	}
+	for _, a := range o.Analyzers {
+		if !slices.Contains(analyzers, a) {
+			return fmt.Errorf("unknown analyzer %q", a)
+		}
+	}
+	return nil
+}
+
+// cacheKey returns the key of the analysis of the binary with digest. The
+// runtime probe, if it runs, depends on its configuration and the host.
+func (o Options) cacheKey(digest string, probe bool) cache.Key {
+	key := cache.Key{Digest: digest, CheckerVersion: cache.CheckerVersion(), Config: "static"}
+	if probe && !o.DisableRuntimeProbe {
+		key.Config = fmt.Sprintf("probe timeout=%s env=%q", o.runtimeTimeout(), o.Env)
+		key.Host = o.HostFingerprint
+	}
+	return key
+}
+
+// layerCacheKey returns the key of the reports of the image layer with
+// digest. Unlike the analysis of a single binary, they also depend on the
+// options that select which files are checked.
+func (o Options) layerCacheKey(digest string) cache.Key {
+	key := o.cacheKey(digest, true)
+	key.Config = fmt.Sprintf("layer %s include=%q exclude=%q maxsize=%d analyzers=%q",
+		key.Config, o.Include, o.Exclude, o.MaxFileSize, o.Analyzers)
+	return key
+}
+
+func (o Options) concurrency() int {
+	if o.Concurrency == 0 {
+		return defaultConcurrency
+	}
+	return o.Concurrency
+}
+
+func (o Options) analysisConcurrency() int {
+	if o.AnalysisConcurrency == 0 {
+		return runtime.GOMAXPROCS(0)
+	}
+	return o.AnalysisConcurrency
+}
+
+func (o Options) runtimeTimeout() time.Duration {
+	if o.RuntimeTimeout == 0 {
+		return defaultRuntimeTimeout
+	}
+	return o.RuntimeTimeout
+}
+
+func (o Options) logger() *slog.Logger {
+	if o.Logger == nil {
+		return slog.New(slog.DiscardHandler)
+	}
+	return o.Logger
+}
+
+// enabled reports whether analyzer a is selected.
+func (o Options) enabled(a Analyzer) bool {
+	return len(o.Analyzers) == 0 || slices.Contains(o.Analyzers, a)
+}
+
+// included reports whether the file at rel, a slash-separated path relative
+// to the scan root, passes the Include and Exclude patterns.
+func (o Options) included(rel string) bool {
+	if matchAny(o.Exclude, rel) {
+		return false
+	}
+	return len(o.Include) == 0 || matchAny(o.Include, rel)
+}
+
+// matchAny reports whether rel matches one of patterns.
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		name := rel
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(rel)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
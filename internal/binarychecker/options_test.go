@@ -0,0 +1,102 @@
+package binarychecker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// writeScript writes an executable shell script, which stands in for a binary
+// as checkRuntimeFIPS only executes the file.
+func writeScript(t *testing.T, body string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "probe.sh")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+body+"\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestCheckRuntimeFIPSEnv(t *testing.T) {
+	script := writeScript(t, `echo "$PROBE_VAR $GOFIPS $HOME"`)
+
+	result, err := checkRuntimeFIPS(context.Background(), script, Options{Env: []string{"PROBE_VAR=set", "PATH=/usr/bin:/bin"}})
+	if err != nil {
+		t.Fatalf("checkRuntimeFIPS failed: %v", err)
+	}
+	if result.Stdout != "set 1 \n" {
+		t.Errorf("Expected only the configured environment plus GOFIPS, got %q", result.Stdout)
+	}
+	if result.Outcome != RuntimeOutcomeCleanExit {
+		t.Errorf("Expected %s, got %s", RuntimeOutcomeCleanExit, result.Outcome)
+	}
+}
+
+func TestCheckRuntimeFIPSTimeout(t *testing.T) {
+	script := writeScript(t, "exec sleep 10")
+
+	start := time.Now()
+	result, err := checkRuntimeFIPS(context.Background(), script, Options{RuntimeTimeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("checkRuntimeFIPS failed: %v", err)
+	}
+	if result.Outcome != RuntimeOutcomeTimeout {
+		t.Errorf("Expected %s, got %s", RuntimeOutcomeTimeout, result.Outcome)
+	}
+	if elapsed := time.Since(start); elapsed > defaultRuntimeTimeout {
+		t.Errorf("Expected the configured timeout to apply, took %s", elapsed)
+	}
+}
+
+func TestCheckRuntimeFIPSErrors(t *testing.T) {
+	// A file without a shebang that is not ELF cannot be executed
+	notExecutable := filepath.Join(t.TempDir(), "data")
+	if err := os.WriteFile(notExecutable, []byte("not a program"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	result, err := checkRuntimeFIPS(context.Background(), notExecutable, Options{})
+	if err != nil {
+		t.Fatalf("checkRuntimeFIPS failed: %v", err)
+	}
+	if result.Outcome != RuntimeOutcomeWrongArchitecture {
+		t.Errorf("Expected %s, got %s", RuntimeOutcomeWrongArchitecture, result.Outcome)
+	}
+
+	_, err = checkRuntimeFIPS(context.Background(), filepath.Join(t.TempDir(), "missing"), Options{})
+	var execErr *report.ExecError
+	if !errors.As(err, &execErr) || execErr.ExitCode != -1 || !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected an ExecError wrapping os.ErrNotExist, got %v", err)
+	}
+}
+
+func TestProbeError(t *testing.T) {
+	tests := []struct {
+		name    string
+		details GoBinaryReportDetails
+		opts    Options
+		want    error
+	}{
+		{name: "foreign", details: staticOnly(GoBinaryReportDetails{ForeignArchitecture: true}), want: report.ErrForeignArch},
+		{name: "timeout", details: GoBinaryReportDetails{RuntimeOutcome: RuntimeOutcomeTimeout}, want: report.ErrRuntimeProbeTimeout},
+		{name: "clean_exit", details: GoBinaryReportDetails{RuntimeOutcome: RuntimeOutcomeCleanExit}},
+		{name: "static", details: staticOnly(GoBinaryReportDetails{})},
+		{
+			name:    "probe_disabled",
+			details: staticOnly(GoBinaryReportDetails{ForeignArchitecture: true}),
+			opts:    Options{DisableRuntimeProbe: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.opts.probeError(tt.details)
+			if tt.want == nil && err != nil || tt.want != nil && !errors.Is(err, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,120 @@
+package binarychecker
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// RuntimeOutcome classifies how a binary behaved when it was executed with GOFIPS=1.
+type RuntimeOutcome = report.RuntimeOutcome
+
+// Runtime outcomes, see package report.
+const (
+	RuntimeOutcomeUnknown            = report.RuntimeOutcomeUnknown
+	RuntimeOutcomeFIPSPanic          = report.RuntimeOutcomeFIPSPanic
+	RuntimeOutcomeOpenSSLLoadFailure = report.RuntimeOutcomeOpenSSLLoadFailure
+	RuntimeOutcomeMissingLibcrypto   = report.RuntimeOutcomeMissingLibcrypto
+	RuntimeOutcomeGlibcMismatch      = report.RuntimeOutcomeGlibcMismatch
+	RuntimeOutcomeWrongArchitecture  = report.RuntimeOutcomeWrongArchitecture
+	RuntimeOutcomeNonFIPSPanic       = report.RuntimeOutcomeNonFIPSPanic
+	RuntimeOutcomeCleanExit          = report.RuntimeOutcomeCleanExit
+	RuntimeOutcomeNonZeroExit        = report.RuntimeOutcomeNonZeroExit
+	RuntimeOutcomeSignal             = report.RuntimeOutcomeSignal
+	RuntimeOutcomeTimeout            = report.RuntimeOutcomeTimeout
+	RuntimeOutcomeSkipped            = report.RuntimeOutcomeSkipped
+	RuntimeOutcomeMissingLibrary     = report.RuntimeOutcomeMissingLibrary
+)
+
+// panicInfo is the parsed form of a Go panic or fatal error written to stderr.
+type panicInfo struct {
+	// Message is the text following "panic: " or "fatal error: ".
+	Message string
+	// Frames lists the function names of the first goroutine trace, innermost first.
+	Frames []string
+}
+
+// parsePanic extracts the panic message and the first goroutine trace from the
+// stderr output of a Go program. It returns false if no panic was found.
+func parsePanic(stderr string) (panicInfo, bool) {
+	var info panicInfo
+	found := false
+	inTrace := false
+
+	scanner := bufio.NewScanner(strings.NewReader(stderr))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case !found && (strings.HasPrefix(line, "panic: ") || strings.HasPrefix(line, "fatal error: ")):
+			_, info.Message, _ = strings.Cut(line, ": ")
+			found = true
+		case found && !inTrace && strings.HasPrefix(line, "goroutine ") && strings.HasSuffix(line, ":"):
+			inTrace = true
+		case inTrace && line == "":
+			// The first goroutine trace ends with an empty line.
+			return info, true
+		case inTrace && !strings.HasPrefix(line, "\t"):
+			// Function lines are unindented, file:line lines are tab-indented.
+			fn := line
+			if i := strings.LastIndex(fn, "("); i > 0 {
+				fn = fn[:i]
+			}
+			info.Frames = append(info.Frames, fn)
+		case found && !inTrace && info.Message != "" && line != "" && !strings.HasPrefix(line, "[signal "):
+			// Multi-line panic values are continued on the following lines.
+			info.Message += "\n" + line
+		}
+	}
+	return info, found
+}
+
+// fromCryptoBackend reports whether any of the frames belongs to the Go crypto backend.
+func (p panicInfo) fromCryptoBackend() bool {
+	for _, fn := range p.Frames {
+		if strings.Contains(fn, "crypto/internal/backend") ||
+			strings.Contains(fn, "golang-fips/openssl") ||
+			strings.Contains(fn, "opensslcrypto") {
+			return true
+		}
+	}
+	return false
+}
+
+// classifyStderr determines the runtime outcome from the stderr output alone.
+// It returns RuntimeOutcomeUnknown and false if stderr does not explain the outcome,
+// in which case the caller falls back to the process exit status.
+func classifyStderr(stderr string) (RuntimeOutcome, panicInfo, bool) {
+	// Dynamic loader errors are printed before the Go runtime starts, so
+	// whichever library is missing, the binary never reached the FIPS check.
+	if strings.Contains(stderr, "error while loading shared libraries") {
+		if strings.Contains(stderr, "libcrypto.so") {
+			return RuntimeOutcomeMissingLibcrypto, panicInfo{}, true
+		}
+		return RuntimeOutcomeMissingLibrary, panicInfo{}, true
+	}
+	if strings.Contains(stderr, "version `GLIBC_") && strings.Contains(stderr, "not found") {
+		return RuntimeOutcomeGlibcMismatch, panicInfo{}, true
+	}
+
+	info, ok := parsePanic(stderr)
+	if !ok {
+		return RuntimeOutcomeUnknown, panicInfo{}, false
+	}
+
+	msg := info.Message
+	switch {
+	case strings.Contains(msg, "FIPS mode requested") ||
+		(strings.Contains(msg, "FIPS") && strings.Contains(msg, "not available in OpenSSL")):
+		return RuntimeOutcomeFIPSPanic, info, true
+	case strings.Contains(msg, "libcrypto.so") &&
+		(strings.Contains(msg, "cannot open shared object file") || strings.Contains(msg, "No such file or directory")):
+		return RuntimeOutcomeMissingLibcrypto, info, true
+	case strings.Contains(msg, "can't initialize OpenSSL") ||
+		strings.Contains(msg, "openssl: can't load") ||
+		info.fromCryptoBackend():
+		return RuntimeOutcomeOpenSSLLoadFailure, info, true
+	}
+	return RuntimeOutcomeNonFIPSPanic, info, true
+}
@@ -0,0 +1,156 @@
+package binarychecker
+
+import (
+	"testing"
+)
+
+func TestClassifyStderr(t *testing.T) {
+	tests := []struct {
+		name     string
+		stderr   string
+		expected RuntimeOutcome
+		matched  bool
+		message  string
+	}{
+		{
+			name: "fips_panic",
+			stderr: `panic: opensslcrypto: FIPS mode requested (system FIPS mode) but not available in OpenSSL 3.0.16 11 Feb 2025
+
+goroutine 1 [running]:
+crypto/internal/backend.init.1()
+	/usr/local/go/src/crypto/internal/backend/openssl_linux.go:66 +0x1f4
+`,
+			expected: RuntimeOutcomeFIPSPanic,
+			matched:  true,
+			message:  "opensslcrypto: FIPS mode requested (system FIPS mode) but not available in OpenSSL 3.0.16 11 Feb 2025",
+		},
+		{
+			name: "missing_libcrypto_panic",
+			stderr: `panic: opensslcrypto: can't initialize OpenSSL libcrypto.so.3: openssl: can't load libcrypto.so.3: libcrypto.so.3: cannot open shared object file: No such file or directory
+
+goroutine 1 [running]:
+crypto/internal/backend.init.1()
+`,
+			expected: RuntimeOutcomeMissingLibcrypto,
+			matched:  true,
+		},
+		{
+			name:     "missing_libcrypto_loader",
+			stderr:   "/app: error while loading shared libraries: libcrypto.so.3: cannot open shared object file: No such file or directory\n",
+			expected: RuntimeOutcomeMissingLibcrypto,
+			matched:  true,
+		},
+		{
+			name:     "missing_other_library_loader",
+			stderr:   "/app: error while loading shared libraries: libzstd.so.1: cannot open shared object file: No such file or directory\n",
+			expected: RuntimeOutcomeMissingLibrary,
+			matched:  true,
+		},
+		{
+			name:     "glibc_mismatch",
+			stderr:   "/app: /lib/x86_64-linux-gnu/libc.so.6: version `GLIBC_2.34' not found (required by /app)\n",
+			expected: RuntimeOutcomeGlibcMismatch,
+			matched:  true,
+		},
+		{
+			name: "openssl_load_failure",
+			stderr: `panic: opensslcrypto: can't initialize OpenSSL libcrypto.so.1.0.2: openssl: OpenSSL version: 1.0.2
+
+goroutine 1 [running]:
+crypto/internal/backend.init.1()
+`,
+			expected: RuntimeOutcomeOpenSSLLoadFailure,
+			matched:  true,
+		},
+		{
+			name: "panic_from_backend_frame",
+			stderr: `panic: runtime error: invalid memory address or nil pointer dereference
+[signal SIGSEGV: segmentation violation code=0x1 addr=0x0 pc=0x4a1f2c]
+
+goroutine 1 [running]:
+vendor/github.com/golang-fips/openssl/v2.Init(...)
+	/usr/local/go/src/vendor/github.com/golang-fips/openssl/v2/openssl.go:69
+`,
+			expected: RuntimeOutcomeOpenSSLLoadFailure,
+			matched:  true,
+		},
+		{
+			name: "non_fips_panic",
+			stderr: `panic: missing required flag --config
+
+goroutine 1 [running]:
+main.main()
+	/src/main.go:12 +0x65
+exit status 2
+`,
+			expected: RuntimeOutcomeNonFIPSPanic,
+			matched:  true,
+			message:  "missing required flag --config",
+		},
+		{
+			name:     "plain_error_output",
+			stderr:   "Error: unknown command\n",
+			expected: RuntimeOutcomeUnknown,
+			matched:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outcome, info, ok := classifyStderr(tt.stderr)
+			if ok != tt.matched {
+				t.Fatalf("expected matched=%t, got %t", tt.matched, ok)
+			}
+			if outcome != tt.expected {
+				t.Errorf("expected outcome %s, got %s", tt.expected, outcome)
+			}
+			if tt.message != "" && info.Message != tt.message {
+				t.Errorf("expected panic message %q, got %q", tt.message, info.Message)
+			}
+		})
+	}
+}
+
+func TestParsePanicFrames(t *testing.T) {
+	stderr := `panic: boom
+
+goroutine 1 [running]:
+main.run(0x1)
+	/src/main.go:20 +0x25
+main.main()
+	/src/main.go:10 +0x13
+
+goroutine 2 [chan receive]:
+main.worker()
+`
+	info, ok := parsePanic(stderr)
+	if !ok {
+		t.Fatal("expected panic to be parsed")
+	}
+	if info.Message != "boom" {
+		t.Errorf("expected message %q, got %q", "boom", info.Message)
+	}
+	expected := []string{"main.run", "main.main"}
+	if len(info.Frames) != len(expected) {
+		t.Fatalf("expected frames %v, got %v", expected, info.Frames)
+	}
+	for i := range expected {
+		if info.Frames[i] != expected[i] {
+			t.Errorf("frame %d: expected %q, got %q", i, expected[i], info.Frames[i])
+		}
+	}
+}
+
+func TestRuntimeOutcomeFailsFIPS(t *testing.T) {
+	failing := map[RuntimeOutcome]bool{
+		RuntimeOutcomeFIPSPanic:          true,
+		RuntimeOutcomeOpenSSLLoadFailure: true,
+		RuntimeOutcomeMissingLibcrypto:   true,
+		RuntimeOutcomeGlibcMismatch:      true,
+	}
+	for o := RuntimeOutcomeUnknown; o <= RuntimeOutcomeSkipped; o++ {
+		if got := o.FailsFIPS(); got != failing[o] {
+			t.Errorf("%s: expected FailsFIPS()=%t, got %t", o, failing[o], got)
+		}
+	}
+}
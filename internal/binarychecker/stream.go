@@ -0,0 +1,492 @@
+package binarychecker
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"iter"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// Progress is a snapshot of a running scan.
+type Progress struct {
+	// FilesWalked is the number of files visited so far
+	FilesWalked int
+	// Candidates is the number of Go binaries found so far
+	Candidates int
+	// ProbesRunning is the number of runtime FIPS checks running right now
+	ProbesRunning int
+	// Completed is the number of binaries whose report is ready
+	Completed int
+	// WalkDone indicates the tree has been walked, so Candidates is final
+	WalkDone bool
+}
+
+// Stream is like CheckWithOptions but yields each report as soon as its check
+// completes, while the tree is still being walked. Reports therefore arrive in
+// completion order. A walk error or the cancellation of ctx is yielded last,
+// with an empty report, as is report.ErrIncompleteScan with Options.Strict.
+// Stopping the iteration early cancels the remaining checks.
+func Stream(ctx context.Context, path string, opts Options) iter.Seq2[BinaryReport, error] {
+	return func(yield func(BinaryReport, error) bool) {
+		src, err := osSource(path)
+		if err != nil {
+			yield(BinaryReport{}, err)
+			return
+		}
+		streamSource(ctx, src, opts, yield)
+	}
+}
+
+// StreamFS is like Stream but scans the tree of fsys, like CheckFS.
+func StreamFS(ctx context.Context, fsys fs.FS, opts Options) iter.Seq2[BinaryReport, error] {
+	return func(yield func(BinaryReport, error) bool) {
+		streamSource(ctx, source{fsys: fsys, root: "."}, opts, yield)
+	}
+}
+
+func streamSource(ctx context.Context, src source, opts Options, yield func(BinaryReport, error) bool) {
+	stopped := false
+	var diagnostics report.ScanDiagnostics
+	err := stream(ctx, src, opts, &diagnostics, func(_ int, r BinaryReport) bool {
+		if !yield(r, nil) {
+			stopped = true
+			return false
+		}
+		return true
+	})
+	if err == nil && opts.Strict {
+		err = diagnostics.Err()
+	}
+	if err != nil && !stopped {
+		yield(BinaryReport{}, err)
+	}
+}
+
+// collect runs a scan like stream and returns the reports in walk order.
+func collect(ctx context.Context, src source, opts Options, diagnostics *report.ScanDiagnostics) ([]BinaryReport, error) {
+	var results []result
+	err := stream(ctx, src, opts, diagnostics, func(idx int, r BinaryReport) bool {
+		results = append(results, result{idx: idx, report: r})
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortFunc(results, func(a, b result) int { return cmp.Compare(a.idx, b.idx) })
+	reports := make([]BinaryReport, len(results))
+	for i, r := range results {
+		reports[i] = r.report
+	}
+	return reports, nil
+}
+
+// source is a tree to scan.
+type source struct {
+	fsys fs.FS
+	// root is the path in fsys the walk starts from
+	root string
+	// osDir is the absolute OS directory fsys is rooted at, or empty if the
+	// files of fsys are not OS files and therefore cannot be executed
+	osDir string
+}
+
+// osSource returns the source for the OS path, which may be a directory or a single file.
+func osSource(path string) (source, error) {
+	// Get absolute path for the root to calculate relative paths
+	absRoot, err := filepath.Abs(path)
+	if err != nil {
+		return source{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	if info, err := os.Stat(absRoot); err == nil && !info.IsDir() {
+		dir := filepath.Dir(absRoot)
+		return source{fsys: os.DirFS(dir), root: filepath.Base(absRoot), osDir: dir}, nil
+	}
+	return source{fsys: os.DirFS(absRoot), root: ".", osDir: absRoot}, nil
+}
+
+// relativePath returns the path of name relative to the walk root.
+func (s source) relativePath(name string) string {
+	return filepath.FromSlash(s.slashPath(name))
+}
+
+// slashPath is like relativePath but returns a slash-separated path.
+func (s source) slashPath(name string) string {
+	switch {
+	case s.root == ".":
+		return name
+	case name == s.root:
+		return "."
+	default:
+		return strings.TrimPrefix(name, s.root+"/")
+	}
+}
+
+// excluded reports whether name is below a virtual filesystem that is never scanned.
+func (s source) excluded(name string) bool {
+	filePath := s.osPath(name)
+	return filePath != "" && shouldExcludePath(filePath+"/")
+}
+
+// resolve returns the name in fsys of the file the symlink name points to.
+// Symlinks in OS directories are resolved inside the scan root, the way a
+// process chrooted to it would, so that absolute targets in a mounted root
+// filesystem never point at the host. Other file systems resolve symlinks
+// themselves, if they support them, and a scanned single file is resolved
+// by the OS.
+func (s source) resolve(name string) (string, error) {
+	if s.osDir == "" || s.root != "." {
+		return name, nil
+	}
+	return rootfs.Rel(s.osDir, name)
+}
+
+// osPath returns the OS path of name, or "" if fsys is not backed by OS files.
+func (s source) osPath(name string) string {
+	if s.osDir == "" {
+		return ""
+	}
+	return filepath.Join(s.osDir, filepath.FromSlash(name))
+}
+
+// progressTracker serializes progress updates.
+type progressTracker struct {
+	mu       sync.Mutex
+	state    Progress
+	callback func(Progress)
+}
+
+func (t *progressTracker) update(f func(*Progress)) {
+	if t.callback == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	f(&t.state)
+	t.callback(t.state)
+}
+
+// candidate is a file found by the walk, with its position in walk order.
+type candidate struct {
+	idx     int
+	name    string
+	symlink bool
+}
+
+// result is a report with the position of its file in walk order.
+type result struct {
+	idx    int
+	report BinaryReport
+}
+
+// probeJob is a Go binary whose analysis awaits the runtime FIPS check.
+type probeJob struct {
+	result
+	entry    *dedupEntry
+	filePath string
+	details  GoBinaryReportDetails
+}
+
+// pipeline is the state shared by the stages of a scan: the walk finds
+// candidate files, up to opts.AnalysisConcurrency analysis workers parse and
+// hash them, and up to opts.Concurrency probe workers execute the binaries
+// that need the runtime FIPS check. Each stage feeds the next through an
+// unbuffered channel, so memory does not grow with the size of the tree.
+type pipeline struct {
+	ctx      context.Context
+	src      source
+	opts     Options
+	logger   *slog.Logger
+	progress *progressTracker
+	dd       *dedup
+	results  chan result
+	probes   chan probeJob
+
+	mu          sync.Mutex
+	diagnostics *report.ScanDiagnostics
+}
+
+// stream walks the tree of src and checks the Go binaries it finds, while the
+// walk continues. Paths that cannot be read are recorded in diagnostics once
+// stream returns. emit is called from the calling goroutine with each report
+// and the position of its file in walk order, which has gaps for files that
+// are not Go binaries; returning false stops the scan.
+func stream(ctx context.Context, src source, opts Options, diagnostics *report.ScanDiagnostics, emit func(idx int, report BinaryReport) bool) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	p := &pipeline{
+		ctx:         ctx,
+		src:         src,
+		opts:        opts,
+		logger:      opts.logger(),
+		progress:    &progressTracker{callback: opts.Progress},
+		dd:          newDedup(),
+		results:     make(chan result),
+		probes:      make(chan probeJob),
+		diagnostics: diagnostics,
+	}
+	candidates := make(chan candidate)
+
+	// Walk the tree and hand candidate files to the analysis workers as they are found
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(candidates)
+		err := p.walk(candidates)
+		if err != nil {
+			err = fmt.Errorf("error walking directory tree: %w", err)
+		}
+		walkErr <- err
+	}()
+
+	var analysis, probes sync.WaitGroup
+	for range opts.analysisConcurrency() {
+		analysis.Add(1)
+		go func() {
+			defer analysis.Done()
+			for c := range candidates {
+				if ctx.Err() == nil {
+					p.analyze(c)
+				}
+			}
+		}()
+	}
+	for range opts.concurrency() {
+		probes.Add(1)
+		go func() {
+			defer probes.Done()
+			for job := range p.probes {
+				if ctx.Err() == nil {
+					p.probe(job)
+				}
+			}
+		}()
+	}
+	go func() {
+		analysis.Wait()
+		p.progress.update(func(p *Progress) { p.WalkDone = true })
+		close(p.probes)
+		probes.Wait()
+		close(p.results)
+	}()
+
+	stopped := false
+	for r := range p.results {
+		if !emit(r.idx, r.report) {
+			stopped = true
+			break
+		}
+	}
+	// Let the walker and workers finish before returning
+	cancel()
+	for range p.results {
+	}
+	err := <-walkErr
+
+	switch {
+	case parent.Err() != nil:
+		// The caller's context was cancelled during execution
+		return parent.Err()
+	case stopped:
+		return nil
+	default:
+		return err
+	}
+}
+
+// walk sends every file of the tree that passes the cheap checks to candidates.
+func (p *pipeline) walk(candidates chan<- candidate) error {
+	src, opts := p.src, p.opts
+	count := 0
+	return fs.WalkDir(src.fsys, src.root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Skip directories/files we can't read, but record them, as
+			// binaries below them are missed. Vanished files are no loss,
+			// unlike a missing scan root.
+			if (name == src.root || !errors.Is(err, fs.ErrNotExist)) && !src.excluded(name) {
+				p.addDiagnostic(name, err)
+			}
+			return nil
+		}
+
+		// Check context cancellation
+		if err := p.ctx.Err(); err != nil {
+			return err
+		}
+
+		rel := src.slashPath(name)
+		// Skip directories, and the trees of excluded ones
+		if d.IsDir() {
+			// Virtual filesystems (e.g., /proc, /sys) are never scanned
+			if src.excluded(name) {
+				return fs.SkipDir
+			}
+			if rel != "." && matchAny(opts.Exclude, rel) {
+				p.logger.Debug("skipping excluded directory", "path", rel)
+				return fs.SkipDir
+			}
+			return nil
+		}
+		p.progress.update(func(p *Progress) { p.FilesWalked++ })
+
+		if src.excluded(name) {
+			return nil
+		}
+		symlink := d.Type()&fs.ModeSymlink != 0
+		if symlink && opts.Symlinks == SymlinkSkip {
+			p.logger.Debug("skipping symlink", "path", rel)
+			return nil
+		}
+		if !opts.included(rel) {
+			p.logger.Debug("skipping excluded file", "path", rel)
+			return nil
+		}
+		if !opts.enabled(AnalyzerGoBinary) {
+			return nil
+		}
+
+		select {
+		case candidates <- candidate{idx: count, name: name, symlink: symlink}:
+			count++
+			return nil
+		case <-p.ctx.Done():
+			return p.ctx.Err()
+		}
+	})
+}
+
+// addDiagnostic records that the file name could not be read.
+func (p *pipeline) addDiagnostic(name string, err error) {
+	p.logger.Debug("cannot read path", "path", p.src.relativePath(name), "error", err)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.diagnostics.Add(p.src.relativePath(name), err)
+}
+
+// analyze checks whether the candidate c is a Go binary and, if so, analyzes
+// it, unless a binary with the same digest was analyzed already. Binaries
+// that need the runtime FIPS check are handed to the probe workers.
+func (p *pipeline) analyze(c candidate) {
+	// A symlink is analyzed at its target, which it is reported as an alias of
+	target := c.name
+	if c.symlink {
+		var err error
+		if target, err = p.src.resolve(c.name); err != nil {
+			p.addDiagnostic(c.name, err)
+			return
+		}
+	}
+	file, err := openBinary(p.src.fsys, target, p.opts.MaxFileSize)
+	if err != nil {
+		p.addDiagnostic(c.name, err)
+	}
+	if file == nil {
+		return
+	}
+	p.progress.update(func(p *Progress) { p.Candidates++ })
+	logger := p.logger.With("path", p.src.relativePath(c.name))
+	logger.Debug("checking binary")
+
+	r := result{idx: c.idx, report: BinaryReport{
+		RelativePath: p.src.relativePath(c.name),
+		Type:         string(AnalyzerGoBinary),
+	}}
+	if c.symlink {
+		r.report.SymlinkTarget = p.src.relativePath(target)
+	}
+	digest, err := p.dd.digest(file)
+	file.close()
+	var entry *dedupEntry
+	if err != nil {
+		// Without a digest, the binary can only be analyzed on its own
+		logger.Debug("cannot hash binary", "error", err)
+		entry = newDedupEntry(p.src.relativePath(target))
+	} else {
+		r.report.Digest = digest
+		var owner bool
+		if entry, owner = p.dd.claim(digest, p.src.relativePath(target)); !owner {
+			// The report is sent once the analysis of the binary is published
+			if !p.dd.follow(entry, r) {
+				p.send(entry.report(r))
+			}
+			return
+		}
+	}
+
+	if p.opts.Cache != nil && digest != "" {
+		if details, ok := p.opts.Cache.Get(p.opts.cacheKey(digest, p.src.osDir != "")); ok {
+			entry.cached = true
+			p.publish(r, entry, details, nil)
+			return
+		}
+	}
+	filePath := p.src.osPath(target)
+	if filePath == "" || p.opts.DisableRuntimeProbe || file.details.ForeignArchitecture {
+		// The binary cannot or must not be executed, so only the static verdict applies
+		p.publish(r, entry, staticOnly(file.details), p.ctx.Err())
+		return
+	}
+	select {
+	case p.probes <- probeJob{result: r, entry: entry, filePath: filePath, details: file.details}:
+	case <-p.ctx.Done():
+	}
+}
+
+// probe completes the analysis of a binary with the runtime FIPS check.
+func (p *pipeline) probe(job probeJob) {
+	p.progress.update(func(p *Progress) { p.ProbesRunning++ })
+	details, err := checkGoBinaryFIPS(p.ctx, job.filePath, job.details, p.opts)
+	p.progress.update(func(p *Progress) { p.ProbesRunning-- })
+	p.publish(job.result, job.entry, details, err)
+}
+
+// publish completes the analysis of entry, stores it in the cache if it
+// succeeded, and sends the reports of the binary at every path found so far.
+// A successful analysis is reported with the error its details imply, if any.
+func (p *pipeline) publish(r result, entry *dedupEntry, details GoBinaryReportDetails, err error) {
+	if err != nil {
+		p.logger.Debug("check failed", "path", r.report.RelativePath, "error", err)
+	} else {
+		if p.opts.Cache != nil && r.report.Digest != "" && !entry.cached {
+			if err := p.opts.Cache.Put(p.opts.cacheKey(r.report.Digest, p.src.osDir != ""), details); err != nil {
+				p.logger.Debug("cannot store analysis in cache", "path", r.report.RelativePath, "error", err)
+			}
+		}
+		err = p.opts.probeError(details)
+	}
+	followers := p.dd.publish(entry, details, err)
+	if !p.send(entry.report(r)) {
+		return
+	}
+	for _, f := range followers {
+		if !p.send(entry.report(f)) {
+			return
+		}
+	}
+}
+
+// send hands a report to the caller of stream. It returns false if the scan was stopped.
+func (p *pipeline) send(r result) bool {
+	select {
+	case p.results <- r:
+		p.progress.update(func(p *Progress) { p.Completed++ })
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
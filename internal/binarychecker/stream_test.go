@@ -0,0 +1,157 @@
+package binarychecker
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// probedSource returns a source of n distinct Go binaries whose runtime checks
+// execute shell scripts instead: the binaries are read from memory, while the
+// files executed at the same paths below the OS directory are scripts. Each
+// script waits until barrier probes run at once, or 10 seconds have passed,
+// and appends the number of probes running to the file counts both when it is
+// released and a moment later, once more probes had the chance to start.
+func probedSource(t *testing.T, n, barrier int) (src source, counts string) {
+	t.Helper()
+	data := testutil.TestBinary(t)
+	dir := t.TempDir()
+	running := filepath.Join(dir, "running")
+	if err := os.Mkdir(running, 0755); err != nil {
+		t.Fatal(err)
+	}
+	counts = filepath.Join(dir, "counts")
+	script := fmt.Sprintf(`#!/bin/sh
+touch %[1]s/$$
+i=0
+while [ "$(ls %[1]s | wc -l)" -lt %[2]d ] && [ $i -lt 200 ]; do sleep 0.05; i=$((i+1)); done
+ls %[1]s | wc -l >> %[3]s
+sleep 0.2
+ls %[1]s | wc -l >> %[3]s
+rm %[1]s/$$
+`, running, barrier, counts)
+
+	bin := filepath.Join(dir, "bin")
+	if err := os.Mkdir(bin, 0755); err != nil {
+		t.Fatal(err)
+	}
+	fsys := fstest.MapFS{}
+	for i := range n {
+		name := fmt.Sprintf("app%d", i)
+		// A trailing byte gives each binary its own digest, so none is deduplicated
+		fsys[name] = &fstest.MapFile{Data: append(slices.Clip(data), byte(i)), Mode: 0755}
+		if err := os.WriteFile(filepath.Join(bin, name), []byte(script), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return source{fsys: fsys, root: ".", osDir: bin}, counts
+}
+
+// maxCount returns the largest number of probes the scripts of probedSource saw running at once.
+func maxCount(t *testing.T, counts string) int {
+	t.Helper()
+	content, err := os.ReadFile(counts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	largest := 0
+	for _, line := range strings.Fields(string(content)) {
+		n, err := strconv.Atoi(line)
+		if err != nil {
+			t.Fatal(err)
+		}
+		largest = max(largest, n)
+	}
+	return largest
+}
+
+func TestStreamConcurrency(t *testing.T) {
+	// The analysis and probe limits are enforced separately: a single analysis
+	// worker does not hold back the probes, and a single probe worker does not
+	// gain from more analysis workers
+	tests := []struct {
+		analysis, probes int
+	}{
+		{analysis: 1, probes: 3},
+		{analysis: 4, probes: 1},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("analysis=%d,probes=%d", tt.analysis, tt.probes), func(t *testing.T) {
+			// The probes wait for each other until the limit is reached, so
+			// reaching it does not depend on how fast the analysis runs
+			src, counts := probedSource(t, 2*tt.probes, tt.probes)
+			opts := Options{
+				AnalysisConcurrency: tt.analysis,
+				Concurrency:         tt.probes,
+				Env:                 []string{"PATH=/usr/bin:/bin"},
+				RuntimeTimeout:      time.Minute,
+			}
+			var diagnostics report.ScanDiagnostics
+			reports, err := collect(context.Background(), src, opts, &diagnostics)
+			if err != nil {
+				t.Fatalf("collect failed: %v", err)
+			}
+			if len(reports) != 2*tt.probes {
+				t.Fatalf("Expected %d reports, got %d", 2*tt.probes, len(reports))
+			}
+			for _, r := range reports {
+				if r.GoBinaryDetails.RuntimeOutcome != RuntimeOutcomeCleanExit || r.Error != nil {
+					t.Errorf("Expected %s to be probed, got %s, %v", r.RelativePath, r.GoBinaryDetails.RuntimeOutcome, r.Error)
+				}
+			}
+			running := maxCount(t, counts)
+			if running > tt.probes {
+				t.Errorf("Expected at most %d probes running at once, got %d", tt.probes, running)
+			}
+			if running < tt.probes {
+				t.Errorf("Expected the limit of %d probes to be reached, got %d", tt.probes, running)
+			}
+		})
+	}
+}
+
+func TestStreamStopEarly(t *testing.T) {
+	// The probes wait for more probes than can run, until they time out
+	src, _ := probedSource(t, 8, 3)
+	before := runtime.NumGoroutine()
+
+	start := time.Now()
+	reports := 0
+	streamSource(context.Background(), src, Options{
+		Concurrency: 2,
+		Env:         []string{"PATH=/usr/bin:/bin"},
+		// The first probe to time out yields the first report
+		RuntimeTimeout: 200 * time.Millisecond,
+	}, func(BinaryReport, error) bool {
+		reports++
+		return false
+	})
+	if reports != 1 {
+		t.Errorf("Expected the scan to stop after the first report, got %d", reports)
+	}
+	// The probes still running are killed rather than waited for
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected stopping to cancel the remaining probes, took %s", elapsed)
+	}
+
+	// The walker and workers are gone once the scan returns; allow the
+	// goroutines that wound them down to exit
+	deadline := time.Now().Add(5 * time.Second)
+	for runtime.NumGoroutine() > before && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if after := runtime.NumGoroutine(); after > before {
+		t.Errorf("Expected no goroutines left behind, had %d before and %d after", before, after)
+	}
+}
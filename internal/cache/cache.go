@@ -0,0 +1,265 @@
+// Package cache stores binary analysis results on disk, so that binaries
+// shared by many images, e.g. kubelet or coredns, are analyzed and probed
+// only once across scans, and layers shared by many images are not scanned
+// again at all.
+//
+// Entries are keyed by the digest of the binary, the checker version, the
+// configuration of the analysis and, for runtime probes, a fingerprint of the
+// host's OpenSSL. An entry therefore becomes unreachable as soon as any of
+// them changes; Prune removes such entries.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// format is the version of the entry format, part of every key.
+const format = "1"
+
+// modulePath is the module whose version is the checker version.
+const modulePath = "github.com/bahe-msft/fips-check"
+
+// Key identifies an analysis result.
+type Key struct {
+	// Digest is the digest of the binary, e.g. "sha256:4f2a..."
+	Digest string `json:"digest"`
+	// CheckerVersion is the version of the checker that analyzed the binary
+	CheckerVersion string `json:"checkerVersion"`
+	// Config describes the options the result depends on, e.g. the probe timeout
+	Config string `json:"config"`
+	// Host is the fingerprint of the host's OpenSSL for runtime probes, and
+	// empty for static analysis, which does not depend on the host
+	Host string `json:"host,omitempty"`
+}
+
+// id returns the file name of the entry for k.
+func (k Key) id() string {
+	h := sha256.Sum256([]byte(strings.Join([]string{format, k.Digest, k.CheckerVersion, k.Config, k.Host}, "\x00")))
+	return hex.EncodeToString(h[:])
+}
+
+// entry is the stored form of a result: the analysis of a binary or the
+// reports of the binaries in an image layer.
+type entry struct {
+	Key     Key                          `json:"key"`
+	Details report.GoBinaryReportDetails `json:"details,omitzero"`
+	Reports []report.BinaryReport        `json:"reports,omitempty"`
+	Created time.Time                    `json:"created"`
+}
+
+// Cache is an on-disk cache in a directory, which is created on the first
+// write. It is safe for concurrent use, also by several processes.
+type Cache struct {
+	dir string
+}
+
+// New returns the cache in dir.
+func New(dir string) *Cache {
+	return &Cache{dir: dir}
+}
+
+// Dir returns the directory of the cache.
+func (c *Cache) Dir() string {
+	return c.dir
+}
+
+func (c *Cache) path(id string) string {
+	return filepath.Join(c.dir, id[:2], id+".json")
+}
+
+// Get returns the result stored for key. A hit marks the entry as used, so
+// that Prune keeps it.
+func (c *Cache) Get(key Key) (report.GoBinaryReportDetails, bool) {
+	e, ok := c.get(key)
+	return e.Details, ok
+}
+
+// Put stores the result for key. Only results of successful analyses should
+// be stored, as errors are often transient.
+func (c *Cache) Put(key Key, details report.GoBinaryReportDetails) error {
+	return c.put(entry{Key: key, Details: details})
+}
+
+// GetLayer returns the reports stored for the image layer with key, like Get.
+// An empty slice is a hit for a layer without binaries.
+func (c *Cache) GetLayer(key Key) ([]report.BinaryReport, bool) {
+	e, ok := c.get(key)
+	if ok && e.Reports == nil {
+		e.Reports = []report.BinaryReport{}
+	}
+	return e.Reports, ok
+}
+
+// PutLayer stores the reports of the binaries in the image layer with key.
+// The layer's key must differ from the keys of binaries, e.g. in Key.Config.
+func (c *Cache) PutLayer(key Key, reports []report.BinaryReport) error {
+	return c.put(entry{Key: key, Reports: reports})
+}
+
+func (c *Cache) get(key Key) (entry, bool) {
+	path := c.path(key.id())
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry{}, false
+	}
+	var e entry
+	if err := json.Unmarshal(data, &e); err != nil || e.Key != key {
+		return entry{}, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return e, true
+}
+
+func (c *Cache) put(e entry) error {
+	e.Created = time.Now().UTC()
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	path := c.path(e.Key.id())
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	// Write atomically, so that concurrent readers never see partial entries
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Stats describes the content of a cache.
+type Stats struct {
+	Entries int
+	Bytes   int64
+}
+
+// Stats counts the entries of the cache.
+func (c *Cache) Stats() (Stats, error) {
+	var s Stats
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		s.Entries++
+		s.Bytes += info.Size()
+		return nil
+	})
+	return s, err
+}
+
+// Clear removes every entry and returns how many were removed.
+func (c *Cache) Clear() (int, error) {
+	removed := 0
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// Prune removes the entries that were not used for maxAge, and those written
+// by other checker versions, which are never used again. It returns how many
+// were removed.
+func (c *Cache) Prune(maxAge time.Duration) (int, error) {
+	version := CheckerVersion()
+	cutoff := time.Now().Add(-maxAge)
+	removed := 0
+	err := c.walk(func(path string, info fs.FileInfo) error {
+		stale := info.ModTime().Before(cutoff)
+		if !stale {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			var e entry
+			stale = json.Unmarshal(data, &e) != nil || e.Key.CheckerVersion != version
+		}
+		if !stale {
+			return nil
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// walk calls f for every entry file. A missing cache directory holds no entries.
+func (c *Cache) walk(f func(path string, info fs.FileInfo) error) error {
+	err := filepath.WalkDir(c.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || filepath.Ext(path) != ".json" {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		return f(path, info)
+	})
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// CheckerVersion returns the version of the checker module in this program,
+// with the VCS revision for development builds.
+func CheckerVersion() string {
+	return checkerVersion()
+}
+
+var checkerVersion = sync.OnceValue(func() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	if info.Main.Path != modulePath {
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				return dep.Version
+			}
+		}
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			version += "+" + s.Value
+		}
+	}
+	return version
+})
+
+// Fingerprint combines the given facts about a host, e.g. the libcrypto path
+// and version, into a short key.
+func Fingerprint(parts ...string) string {
+	h := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(h[:16])
+}
@@ -0,0 +1,102 @@
+package cache
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/bahe-msft/fips-check/report"
+)
+
+func testKey(digest string) Key {
+	return Key{Digest: digest, CheckerVersion: CheckerVersion(), Config: "static"}
+}
+
+func TestPutGet(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "cache"))
+	details := report.GoBinaryReportDetails{GoVersion: "go1.24.4", UseSystemcrypto: true, CGOEnabled: true}
+	key := testKey("sha256:aa")
+
+	if _, ok := c.Get(key); ok {
+		t.Fatal("Expected a miss in an empty cache")
+	}
+	if err := c.Put(key, details); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	got, ok := c.Get(key)
+	if !ok {
+		t.Fatal("Expected a hit after Put")
+	}
+	if got != details {
+		t.Errorf("Expected %+v, got %+v", details, got)
+	}
+
+	for name, other := range map[string]Key{
+		"digest":  testKey("sha256:bb"),
+		"version": {Digest: key.Digest, CheckerVersion: "v0.0.0-other", Config: key.Config},
+		"config":  {Digest: key.Digest, CheckerVersion: key.CheckerVersion, Config: "probe"},
+		"host":    {Digest: key.Digest, CheckerVersion: key.CheckerVersion, Config: key.Config, Host: "other"},
+	} {
+		if _, ok := c.Get(other); ok {
+			t.Errorf("Expected a miss for a different %s", name)
+		}
+	}
+}
+
+func TestStatsClearPrune(t *testing.T) {
+	c := New(t.TempDir())
+	for _, key := range []Key{
+		testKey("sha256:aa"),
+		testKey("sha256:bb"),
+		{Digest: "sha256:aa", CheckerVersion: "v0.0.0-other", Config: "static"},
+	} {
+		if err := c.Put(key, report.GoBinaryReportDetails{}); err != nil {
+			t.Fatalf("Put failed: %v", err)
+		}
+	}
+	stats, err := c.Stats()
+	if err != nil {
+		t.Fatalf("Stats failed: %v", err)
+	}
+	if stats.Entries != 3 || stats.Bytes == 0 {
+		t.Errorf("Expected 3 entries, got %+v", stats)
+	}
+
+	// Age one entry of the current version; the other version is stale anyway
+	old := time.Now().Add(-48 * time.Hour)
+	if err := os.Chtimes(c.path(testKey("sha256:bb").id()), old, old); err != nil {
+		t.Fatal(err)
+	}
+	removed, err := c.Prune(24 * time.Hour)
+	if err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+	if removed != 2 {
+		t.Errorf("Expected Prune to remove 2 entries, removed %d", removed)
+	}
+	if _, ok := c.Get(testKey("sha256:aa")); !ok {
+		t.Error("Expected the recent entry to survive Prune")
+	}
+
+	removed, err = c.Clear()
+	if err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("Expected Clear to remove 1 entry, removed %d", removed)
+	}
+	if stats, err := c.Stats(); err != nil || stats.Entries != 0 {
+		t.Errorf("Expected an empty cache after Clear, got %+v, %v", stats, err)
+	}
+}
+
+func TestMissingDir(t *testing.T) {
+	c := New(filepath.Join(t.TempDir(), "missing"))
+	if stats, err := c.Stats(); err != nil || stats.Entries != 0 {
+		t.Errorf("Expected an empty cache, got %+v, %v", stats, err)
+	}
+	if removed, err := c.Prune(time.Hour); err != nil || removed != 0 {
+		t.Errorf("Expected nothing to prune, got %d, %v", removed, err)
+	}
+}
@@ -0,0 +1,132 @@
+// Package cmvp maps cryptographic modules to their CMVP (Cryptographic Module
+// Validation Program) certificates. A catalog is embedded in the binary and can
+// be extended or corrected with a catalog file, as certificate statuses change
+// more often than the checker is released.
+package cmvp
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+)
+
+// Module identifies a family of cryptographic modules.
+type Module string
+
+const (
+	// OpenSSLFIPSProvider is the OpenSSL 3 "fips" provider (fips.so)
+	OpenSSLFIPSProvider Module = "openssl-fips-provider"
+	// SymCryptOpenSSL is the SymCrypt-OpenSSL provider (symcryptprovider.so)
+	SymCryptOpenSSL Module = "symcrypt-openssl"
+)
+
+// Status is the validation status of a certificate.
+type Status string
+
+const (
+	StatusActive     Status = "active"
+	StatusHistorical Status = "historical"
+	StatusInProcess  Status = "in-process"
+)
+
+// Entry maps module versions or distro package builds to a certificate.
+type Entry struct {
+	Module Module `json:"module"`
+	// Name is the module name as listed on the certificate
+	Name string `json:"name"`
+	// Versions are the module versions covered by the certificate
+	Versions []string `json:"versions,omitempty"`
+	// Builds are distro package builds covered by the certificate, matched
+	// against the provider build info, e.g. "3.0.7-27.el9"
+	Builds []string `json:"builds,omitempty"`
+	// Certificate is the CMVP certificate number, 0 while in process
+	Certificate int    `json:"certificate,omitempty"`
+	Status      Status `json:"status"`
+}
+
+// URL returns the certificate page on the NIST CMVP site, or an empty string.
+func (e Entry) URL() string {
+	if e.Certificate == 0 {
+		return ""
+	}
+	return fmt.Sprintf("https://csrc.nist.gov/projects/cryptographic-module-validation-program/certificate/%d", e.Certificate)
+}
+
+// Catalog is a list of validated module entries.
+type Catalog struct {
+	// Updated is the date the catalog was last reviewed
+	Updated string  `json:"updated"`
+	Entries []Entry `json:"entries"`
+}
+
+//go:embed catalog.json
+var embedded []byte
+
+// Default returns the catalog embedded in the binary.
+func Default() *Catalog {
+	c, err := Parse(embedded)
+	if err != nil {
+		panic("cmvp: invalid embedded catalog: " + err.Error())
+	}
+	return c
+}
+
+// Parse parses a JSON catalog.
+func Parse(data []byte) (*Catalog, error) {
+	var c Catalog
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("cmvp: invalid catalog: %w", err)
+	}
+	for i, e := range c.Entries {
+		if e.Module == "" || (len(e.Versions) == 0 && len(e.Builds) == 0) {
+			return nil, fmt.Errorf("cmvp: catalog entry %d needs a module and versions or builds", i)
+		}
+		switch e.Status {
+		case StatusActive, StatusHistorical, StatusInProcess:
+		default:
+			return nil, fmt.Errorf("cmvp: catalog entry %d has unknown status %q", i, e.Status)
+		}
+	}
+	return &c, nil
+}
+
+// Load reads a catalog file and layers it over the embedded catalog, so
+// its entries take precedence over the embedded ones.
+func Load(name string) (*Catalog, error) {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	override, err := Parse(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	base := Default()
+	return &Catalog{
+		Updated: override.Updated,
+		Entries: append(override.Entries, base.Entries...),
+	}, nil
+}
+
+// Lookup returns the first entry for module that lists build or version.
+// Builds are matched first, as a distro build can be validated separately
+// from the upstream version it is based on.
+func (c *Catalog) Lookup(module Module, version, build string) (Entry, bool) {
+	if build != "" {
+		for _, e := range c.Entries {
+			if e.Module == module && slices.Contains(e.Builds, build) {
+				return e, true
+			}
+		}
+	}
+	if version != "" {
+		for _, e := range c.Entries {
+			if e.Module == module && slices.Contains(e.Versions, version) {
+				return e, true
+			}
+		}
+	}
+	return Entry{}, false
+}
@@ -0,0 +1,117 @@
+package cmvp
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDefaultCatalog(t *testing.T) {
+	catalog := Default()
+	entry, ok := catalog.Lookup(OpenSSLFIPSProvider, "3.0.8", "")
+	if !ok {
+		t.Fatal("Expected OpenSSL FIPS provider 3.0.8 in the embedded catalog")
+	}
+	if entry.Certificate != 4282 || entry.Status != StatusActive {
+		t.Errorf("Unexpected entry %+v", entry)
+	}
+	if entry.URL() == "" {
+		t.Error("Expected a certificate URL")
+	}
+	if _, ok := catalog.Lookup(OpenSSLFIPSProvider, "3.0.17", ""); ok {
+		t.Error("Expected OpenSSL FIPS provider 3.0.17 not to be in the catalog")
+	}
+	if _, ok := catalog.Lookup(SymCryptOpenSSL, "3.0.8", ""); ok {
+		t.Error("Expected lookups to match the module")
+	}
+
+	// SymCrypt-OpenSSL is validated as part of the SymCrypt library
+	entry, ok = catalog.Lookup(SymCryptOpenSSL, "1.5.1", "")
+	if !ok || entry.Name != "Microsoft SymCrypt Cryptographic Library" || entry.Status != StatusActive {
+		t.Errorf("Expected an active SymCrypt entry for 1.5.1, got %+v", entry)
+	}
+	// A distro build is matched even though its upstream version is not validated
+	entry, ok = catalog.Lookup(OpenSSLFIPSProvider, "3.0.7", "3.0.7-16.el9_2")
+	if !ok || entry.Name != "Red Hat Enterprise Linux 9 - OpenSSL FIPS Provider" {
+		t.Errorf("Expected the RHEL 9 entry for build 3.0.7-16.el9_2, got %+v", entry)
+	}
+	if entry, _ := catalog.Lookup(OpenSSLFIPSProvider, "", "3.0.1-43.el9_0"); entry.Status != StatusHistorical {
+		t.Errorf("Expected the RHEL 9.0 build to be historical, got %+v", entry)
+	}
+	if entry, _ := catalog.Lookup(SymCryptOpenSSL, "1.8.0", ""); entry.Status != StatusInProcess || entry.URL() != "" {
+		t.Errorf("Expected an in-process SymCrypt entry without URL, got %+v", entry)
+	}
+}
+
+func TestLoadOverridesEmbeddedCatalog(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "catalog.json")
+	data := `{
+		"updated": "2030-01-01",
+		"entries": [
+			{"module": "openssl-fips-provider", "name": "OpenSSL FIPS Provider", "versions": ["3.0.8"], "certificate": 4282, "status": "historical"},
+			{"module": "symcrypt-openssl", "name": "SymCrypt-OpenSSL", "builds": ["1.4.1-1.azl3"], "status": "in-process"}
+		]
+	}`
+	if err := os.WriteFile(name, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := Load(name)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if catalog.Updated != "2030-01-01" {
+		t.Errorf("Expected the override date, got %s", catalog.Updated)
+	}
+	if entry, _ := catalog.Lookup(OpenSSLFIPSProvider, "3.0.8", ""); entry.Status != StatusHistorical {
+		t.Errorf("Expected the override to take precedence, got %+v", entry)
+	}
+	if _, ok := catalog.Lookup(OpenSSLFIPSProvider, "3.1.2", ""); !ok {
+		t.Error("Expected embedded entries to remain available")
+	}
+	entry, ok := catalog.Lookup(SymCryptOpenSSL, "1.4.1", "1.4.1-1.azl3")
+	if !ok || entry.URL() != "" {
+		t.Errorf("Expected the in-process build entry without URL, got %+v", entry)
+	}
+}
+
+func TestParseRejectsInvalidEntries(t *testing.T) {
+	invalid := []string{
+		`{"entries": [{"module": "openssl-fips-provider", "status": "active"}]}`,
+		`{"entries": [{"module": "openssl-fips-provider", "versions": ["3.0.8"], "status": "revoked"}]}`,
+		`{"entries": `,
+	}
+	for _, data := range invalid {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("Expected error for %s", data)
+		}
+	}
+}
+
+func TestFindModules(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "usr", "lib", "x86_64-linux-gnu", "ossl-modules")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	// A module that is not an ELF file is reported without a version
+	if err := os.WriteFile(filepath.Join(dir, "symcryptprovider.so"), []byte("not elf"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// lib is a symlink to usr/lib, as on merged-/usr distros; the module must be reported once
+	if err := os.Symlink("usr/lib", filepath.Join(root, "lib")); err != nil {
+		t.Fatal(err)
+	}
+
+	modules, err := FindModules(root)
+	if err != nil {
+		t.Fatalf("FindModules failed: %v", err)
+	}
+	if len(modules) != 1 {
+		t.Fatalf("Expected 1 module, got %+v", modules)
+	}
+	m := modules[0]
+	if m.Module != SymCryptOpenSSL || m.Version != "" || m.Path != "/usr/lib/x86_64-linux-gnu/ossl-modules/symcryptprovider.so" {
+		t.Errorf("Unexpected module %+v", m)
+	}
+}
@@ -0,0 +1,129 @@
+package cmvp
+
+import (
+	"bytes"
+	"debug/elf"
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+)
+
+// Detected is a cryptographic module found on a host or in an image.
+type Detected struct {
+	Module  Module
+	Version string
+	// Build is the provider build info, which includes the distro build on some distros
+	Build string
+	// Path is the module file inside the root filesystem, if it was found on disk
+	Path string
+}
+
+// ProviderModule returns the module implemented by the OpenSSL provider with the given name.
+func ProviderModule(provider string) (Module, bool) {
+	switch provider {
+	case "fips":
+		return OpenSSLFIPSProvider, true
+	case "symcryptprovider":
+		return SymCryptOpenSSL, true
+	}
+	return "", false
+}
+
+// moduleFiles maps provider shared objects to the module they implement.
+var moduleFiles = map[string]Module{
+	"fips.so":             OpenSSLFIPSProvider,
+	"symcryptprovider.so": SymCryptOpenSSL,
+}
+
+// FileModule returns the module implemented by the provider shared object at
+// name, e.g. "/usr/lib64/ossl-modules/fips.so". Only the path is looked at.
+func FileModule(name string) (Module, bool) {
+	if path.Base(path.Dir(name)) != "ossl-modules" {
+		return "", false
+	}
+	module, ok := moduleFiles[path.Base(name)]
+	return module, ok
+}
+
+// libDirs are the library directories whose ossl-modules subdirectory is searched.
+// Multiarch subdirectories such as /usr/lib/x86_64-linux-gnu are added at runtime.
+var libDirs = []string{"/usr/lib", "/usr/lib64", "/usr/local/lib", "/usr/local/lib64", "/lib", "/lib64"}
+
+// FindModules searches the provider module directories of the root filesystem
+// at root for validated module candidates and reads their versions.
+func FindModules(root string) ([]Detected, error) {
+	var dirs []string
+	for _, dir := range libDirs {
+		dirs = append(dirs, dir)
+		hostDir, err := rootfs.Join(root, dir)
+		if err != nil {
+			return nil, err
+		}
+		entries, _ := os.ReadDir(hostDir)
+		for _, entry := range entries {
+			if entry.IsDir() && strings.Contains(entry.Name(), "-linux-") {
+				dirs = append(dirs, path.Join(dir, entry.Name()))
+			}
+		}
+	}
+
+	var found []Detected
+	seen := map[string]bool{}
+	for _, dir := range dirs {
+		for file, module := range moduleFiles {
+			name := path.Join(dir, "ossl-modules", file)
+			hostPath, err := rootfs.Join(root, name)
+			if err != nil {
+				return nil, err
+			}
+			if seen[hostPath] {
+				continue
+			}
+			seen[hostPath] = true
+
+			version, err := ModuleVersion(hostPath)
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			found = append(found, Detected{Module: module, Version: version, Path: name})
+		}
+	}
+	return found, nil
+}
+
+// versionPattern matches a bare version string such as "3.0.8".
+var versionPattern = regexp.MustCompile(`^\d+\.\d+\.\d+$`)
+
+// ModuleVersion reads the version a provider reports for itself, which is
+// stored as a bare string in its read-only data. Unreadable modules are
+// reported with an empty version.
+func ModuleVersion(name string) (string, error) {
+	if _, err := os.Stat(name); err != nil {
+		return "", err
+	}
+	f, err := elf.Open(name)
+	if err != nil {
+		return "", nil
+	}
+	defer f.Close()
+
+	section := f.Section(".rodata")
+	if section == nil {
+		return "", nil
+	}
+	data, err := section.Data()
+	if err != nil {
+		return "", nil
+	}
+	for _, s := range bytes.Split(data, []byte{0}) {
+		if versionPattern.Match(s) {
+			return string(s), nil
+		}
+	}
+	return "", nil
+}
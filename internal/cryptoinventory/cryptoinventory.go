@@ -0,0 +1,241 @@
+// Package cryptoinventory locates the OpenSSL runtime in a root filesystem:
+// every libcrypto and libssl, the FIPS provider modules and their configuration.
+// It replaces guessing from the presence of the openssl command, which an
+// image can have without a FIPS provider or lack despite shipping libcrypto.
+package cryptoinventory
+
+import (
+	"context"
+	"debug/elf"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/opensslconf"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// Kind is the kind of an inventory item.
+type Kind string
+
+const (
+	KindLibcrypto        Kind = "libcrypto"
+	KindLibssl           Kind = "libssl"
+	KindFIPSProvider     Kind = "fips-provider"
+	KindFIPSModuleConfig Kind = "fipsmodule-config"
+	KindOpenSSLConfig    Kind = "openssl-config"
+)
+
+// Item is a file of the OpenSSL runtime.
+type Item struct {
+	Kind Kind
+	// Path is the location inside the root, e.g. "/usr/lib64/libcrypto.so.3"
+	Path string
+	// LinkTarget is set if the file is a symlink, which is not inspected further
+	LinkTarget string
+	// Module is, for a FIPS provider, the module it implements
+	Module cmvp.Module
+	// Soname is the DT_SONAME of a shared library, e.g. "libcrypto.so.3"
+	Soname string
+	// Version is the version text of a library, e.g. "OpenSSL 3.0.8 7 Feb 2023",
+	// or the version of the FIPS provider, e.g. "3.0.8"
+	Version string
+	// Activated reports, for a fipsmodule.cnf, whether it activates the provider
+	Activated bool
+	// Providers lists, for an openssl.cnf, the providers it activates,
+	// including those activated by the files it includes
+	Providers []string
+	// DefaultProperties is, for an openssl.cnf, its default_properties, e.g. "fips=yes"
+	DefaultProperties string
+	// Err is set if the file could not be inspected
+	Err error
+}
+
+// Inventory lists the OpenSSL runtime files found in a root filesystem.
+type Inventory struct {
+	Root  string
+	Items []Item
+	// Diagnostics records the paths that could not be read, below which
+	// runtime files may be missing from Items
+	Diagnostics report.ScanDiagnostics
+}
+
+// has reports whether the inventory holds a file, not just a symlink, of kind.
+func (inv Inventory) has(kind Kind) bool {
+	for _, item := range inv.Items {
+		if item.Kind == kind && item.LinkTarget == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasLibcrypto reports whether a libcrypto shared library was found.
+func (inv Inventory) HasLibcrypto() bool {
+	return inv.has(KindLibcrypto)
+}
+
+// HasFIPSProvider reports whether a FIPS provider module, such as the OpenSSL
+// FIPS provider or SymCrypt-OpenSSL, was found.
+func (inv Inventory) HasFIPSProvider() bool {
+	return inv.has(KindFIPSProvider)
+}
+
+// HasModule reports whether a provider module implementing module was found.
+func (inv Inventory) HasModule(module cmvp.Module) bool {
+	for _, item := range inv.Items {
+		if item.Kind == KindFIPSProvider && item.Module == module && item.LinkTarget == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// HasFIPSModuleConfig reports whether a fipsmodule.cnf was found.
+func (inv Inventory) HasFIPSModuleConfig() bool {
+	return inv.has(KindFIPSModuleConfig)
+}
+
+// Scan walks the root filesystem at root and inventories its OpenSSL runtime.
+// Virtual filesystems are skipped when scanning the host root. Paths that
+// cannot be read are recorded in the inventory's Diagnostics.
+func Scan(ctx context.Context, root string) (Inventory, error) {
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return Inventory{}, fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	inv := Inventory{Root: root}
+
+	err = filepath.WalkDir(absRoot, func(filePath string, d fs.DirEntry, err error) error {
+		rel, relErr := filepath.Rel(absRoot, filePath)
+		if relErr != nil {
+			return nil
+		}
+		name := "/" + filepath.ToSlash(rel)
+		if err != nil {
+			// Skip directories/files we can't read, but record them, as runtime
+			// files below them are missed. Vanished files are no loss, unlike a
+			// missing root.
+			if filePath == absRoot || !errors.Is(err, fs.ErrNotExist) {
+				inv.Diagnostics.Add(name, err)
+			}
+			return nil
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if absRoot == "/" && (name == "/proc" || name == "/sys" || name == "/dev") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		kind, ok := Classify(name)
+		if !ok {
+			return nil
+		}
+		item := Item{Kind: kind, Path: name}
+		item.Module, _ = cmvp.FileModule(name)
+		if d.Type()&fs.ModeSymlink != 0 {
+			item.LinkTarget, item.Err = os.Readlink(filePath)
+		} else {
+			inspect(absRoot, filePath, &item)
+		}
+		inv.Items = append(inv.Items, item)
+		return nil
+	})
+	if err != nil {
+		return inv, fmt.Errorf("error walking directory tree: %w", err)
+	}
+	return inv, nil
+}
+
+// Classify returns the kind of the file at name, if it belongs to the OpenSSL
+// runtime. Only the path is looked at, so name need not exist.
+func Classify(name string) (Kind, bool) {
+	if _, ok := cmvp.FileModule(name); ok {
+		return KindFIPSProvider, true
+	}
+	base := path.Base(name)
+	switch {
+	case base == "libcrypto.so" || strings.HasPrefix(base, "libcrypto.so."):
+		return KindLibcrypto, true
+	case base == "libssl.so" || strings.HasPrefix(base, "libssl.so."):
+		return KindLibssl, true
+	case base == "fipsmodule.cnf":
+		return KindFIPSModuleConfig, true
+	case base == "openssl.cnf":
+		return KindOpenSSLConfig, true
+	}
+	return "", false
+}
+
+// inspect reads the version information or configuration of the file at
+// filePath, inside the root filesystem at root, into item.
+func inspect(root, filePath string, item *Item) {
+	switch item.Kind {
+	case KindLibcrypto, KindLibssl:
+		item.Soname, item.Version, item.Err = libraryVersion(filePath)
+	case KindFIPSProvider:
+		item.Version, item.Err = cmvp.ModuleVersion(filePath)
+	case KindFIPSModuleConfig:
+		conf, err := opensslconf.Load("", filePath)
+		if err != nil {
+			item.Err = err
+			return
+		}
+		// fipsmodule.cnf holds a single section for the provider, e.g. [fips_sect]
+		for _, section := range conf.Sections() {
+			if opensslconf.Activates(conf.Get(section, "activate")) {
+				item.Activated = true
+			}
+		}
+	case KindOpenSSLConfig:
+		// Included files are resolved inside the root, like absolute paths of the image
+		conf, err := opensslconf.Load(root, item.Path)
+		if err != nil {
+			item.Err = err
+			return
+		}
+		item.Providers = conf.ActivatedProviders()
+		item.DefaultProperties = conf.DefaultProperties()
+	}
+}
+
+// versionTextPattern matches the OpenSSL version text, e.g. "OpenSSL 3.0.8 7 Feb 2023"
+// or "OpenSSL 1.1.1k  FIPS 25 Mar 2021".
+var versionTextPattern = regexp.MustCompile(`^OpenSSL \d+\.\d+\.\d+[a-z]*(\s.*)?$`)
+
+// libraryVersion returns the soname and the OpenSSL version text of a shared library.
+// libssl does not embed the version text, so only its soname is reported.
+func libraryVersion(name string) (soname, version string, err error) {
+	f, err := elf.Open(name)
+	if err != nil {
+		return "", "", err
+	}
+	defer f.Close()
+
+	if sonames, err := f.DynString(elf.DT_SONAME); err == nil && len(sonames) > 0 {
+		soname = sonames[0]
+	}
+	if section := f.Section(".rodata"); section != nil {
+		data, err := section.Data()
+		if err != nil {
+			return soname, "", err
+		}
+		for _, s := range strings.Split(string(data), "\x00") {
+			if versionTextPattern.MatchString(s) {
+				return soname, s, nil
+			}
+		}
+	}
+	return soname, "", nil
+}
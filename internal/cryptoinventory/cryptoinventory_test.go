@@ -0,0 +1,177 @@
+package cryptoinventory
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/testutil"
+)
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		kind Kind
+		ok   bool
+	}{
+		{"/usr/lib64/libcrypto.so.3", KindLibcrypto, true},
+		{"/usr/lib/libcrypto.so", KindLibcrypto, true},
+		{"/usr/lib/libssl.so.1.1", KindLibssl, true},
+		{"/usr/lib64/ossl-modules/fips.so", KindFIPSProvider, true},
+		{"/usr/lib/x86_64-linux-gnu/ossl-modules/symcryptprovider.so", KindFIPSProvider, true},
+		{"/opt/app/symcryptprovider.so", "", false},
+		{"/etc/pki/tls/fipsmodule.cnf", KindFIPSModuleConfig, true},
+		{"/etc/pki/tls/openssl.cnf", KindOpenSSLConfig, true},
+		{"/opt/app/fips.so", "", false},
+		{"/usr/lib/libcrypto.a", "", false},
+		{"/usr/bin/openssl", "", false},
+	}
+	for _, tt := range tests {
+		kind, ok := Classify(tt.name)
+		if kind != tt.kind || ok != tt.ok {
+			t.Errorf("Classify(%q) = %q, %t; expected %q, %t", tt.name, kind, ok, tt.kind, tt.ok)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"usr/lib64/libcrypto.so.3":       "not an ELF file",
+		"usr/lib64/ossl-modules/fips.so": "not an ELF file",
+		"etc/ssl/fipsmodule.cnf":         "[fips_sect]\nactivate = 1\ninstall-version = 1\n",
+		"usr/bin/openssl":                "cli",
+		"etc/ssl/openssl.cnf": "openssl_conf = openssl_init\n.include /etc/ssl/fipsmodule.cnf\n" +
+			"[openssl_init]\nproviders = provider_sect\nalg_section = algorithm_sect\n" +
+			"[provider_sect]\nfips = fips_sect\n[algorithm_sect]\ndefault_properties = fips=yes\n",
+	}
+	testutil.WriteFiles(t, root, files)
+	if err := os.Symlink("libcrypto.so.3", filepath.Join(root, "usr/lib64/libcrypto.so")); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !inv.HasLibcrypto() || !inv.HasFIPSProvider() || !inv.HasFIPSModuleConfig() {
+		t.Errorf("Expected libcrypto, FIPS provider and config, got %+v", inv.Items)
+	}
+
+	items := map[string]Item{}
+	for _, item := range inv.Items {
+		items[item.Path] = item
+	}
+	if len(items) != 5 {
+		t.Errorf("Expected 5 items, got %+v", inv.Items)
+	}
+	if link := items["/usr/lib64/libcrypto.so"]; link.LinkTarget != "libcrypto.so.3" {
+		t.Errorf("Expected symlink to libcrypto.so.3, got %+v", link)
+	}
+	if lib := items["/usr/lib64/libcrypto.so.3"]; lib.Err == nil {
+		t.Error("Expected an error for a library that is not an ELF file")
+	}
+	if conf := items["/etc/ssl/fipsmodule.cnf"]; !conf.Activated {
+		t.Errorf("Expected fipsmodule.cnf to activate the provider, got %+v", conf)
+	}
+	// The include of fipsmodule.cnf is resolved inside the root
+	if conf := items["/etc/ssl/openssl.cnf"]; !slices.Equal(conf.Providers, []string{"fips"}) || conf.DefaultProperties != "fips=yes" {
+		t.Errorf("Expected openssl.cnf to activate the fips provider by default, got %+v", conf)
+	}
+}
+
+func TestScanSymCrypt(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteFiles(t, root, map[string]string{
+		"usr/lib/libcrypto.so.3":                   "not an ELF file",
+		"usr/lib/ossl-modules/symcryptprovider.so": "not an ELF file",
+	})
+
+	inv, err := Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if !inv.HasFIPSProvider() || !inv.HasModule(cmvp.SymCryptOpenSSL) || inv.HasModule(cmvp.OpenSSLFIPSProvider) {
+		t.Errorf("Expected SymCrypt-OpenSSL as the only FIPS provider, got %+v", inv.Items)
+	}
+}
+
+func TestScanDiagnostics(t *testing.T) {
+	// A missing root is recorded rather than yielding an empty inventory
+	missing := filepath.Join(t.TempDir(), "missing")
+	inv, err := Scan(context.Background(), missing)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if inv.Diagnostics.Count != 1 || !errors.Is(inv.Diagnostics.Errors[0], fs.ErrNotExist) {
+		t.Errorf("Expected the missing root to be recorded, got %+v", inv.Diagnostics)
+	}
+
+	if os.Geteuid() == 0 {
+		t.Skip("Directory permissions do not apply to root")
+	}
+	root := t.TempDir()
+	testutil.WriteFiles(t, root, map[string]string{
+		"usr/lib64/libcrypto.so.3":   "not an ELF file",
+		"usr/local/lib/libcrypto.so": "not an ELF file",
+	})
+	locked := filepath.Join(root, "usr", "local")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chmod(locked, 0755) })
+
+	inv, err = Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if inv.Diagnostics.Count != 1 || inv.Diagnostics.Errors[0].Path != "/usr/local" {
+		t.Errorf("Expected /usr/local to be recorded, got %+v", inv.Diagnostics)
+	}
+	if len(inv.Items) != 1 {
+		t.Errorf("Expected only the readable libcrypto, got %+v", inv.Items)
+	}
+}
+
+func TestScanWithoutLibcrypto(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "usr", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "usr", "bin", "openssl"), []byte("cli"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	inv, err := Scan(context.Background(), root)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	if inv.HasLibcrypto() || len(inv.Items) != 0 {
+		t.Errorf("Expected an empty inventory, got %+v", inv.Items)
+	}
+}
+
+func TestLibraryVersion(t *testing.T) {
+	matches, _ := filepath.Glob("/usr/lib*/*/libcrypto.so.3")
+	more, _ := filepath.Glob("/usr/lib*/libcrypto.so.3")
+	matches = append(matches, more...)
+	if len(matches) == 0 {
+		t.Skip("No libcrypto.so.3 on this host")
+	}
+
+	soname, version, err := libraryVersion(matches[0])
+	if err != nil {
+		t.Fatalf("libraryVersion failed: %v", err)
+	}
+	if soname != "libcrypto.so.3" {
+		t.Errorf("Expected soname libcrypto.so.3, got %q", soname)
+	}
+	if !versionTextPattern.MatchString(version) {
+		t.Errorf("Expected OpenSSL version text, got %q", version)
+	}
+}
@@ -0,0 +1,94 @@
+package ociimage
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+)
+
+// defaultPath is the PATH container runtimes use when the image sets none.
+const defaultPath = "/usr/local/sbin:/usr/local/bin:/usr/sbin:/usr/bin:/sbin:/bin"
+
+// ErrNoCommand is returned by Config.Entrypoint for images that set neither
+// Entrypoint nor Cmd.
+var ErrNoCommand = errors.New("image has no entrypoint or cmd")
+
+// Command returns the command a container of the image runs by default:
+// its Entrypoint followed by its Cmd.
+func (c Config) Command() []string {
+	return append(append([]string(nil), c.Config.Entrypoint...), c.Config.Cmd...)
+}
+
+// Getenv returns the value of the variable key in the image environment.
+func (c Config) Getenv(key string) string {
+	for _, kv := range c.Config.Env {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// Entrypoint resolves the executable of the image's default command in the
+// root filesystem unpacked in the directory root, the way a container runtime
+// would: a name without a slash is looked up in the image PATH, other names
+// are relative to the image WorkingDir. It returns the slash-separated path
+// of the executable relative to root, with symlinks resolved inside root
+// except for the final component.
+func (c Config) Entrypoint(root string) (string, error) {
+	command := c.Command()
+	if len(command) == 0 || command[0] == "" {
+		return "", ErrNoCommand
+	}
+	name := command[0]
+	workDir := path.Join("/", c.Config.WorkingDir)
+	if strings.Contains(name, "/") {
+		return executable(root, path.Join(workDir, name))
+	}
+
+	pathEnv := defaultPath
+	for _, kv := range c.Config.Env {
+		if strings.HasPrefix(kv, "PATH=") {
+			pathEnv = c.Getenv("PATH")
+			break
+		}
+	}
+	for _, dir := range filepath.SplitList(pathEnv) {
+		// An empty PATH entry is the working directory
+		if p, err := executable(root, path.Join(workDir, dir, name)); err == nil {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("%s: executable file not found in $PATH", name)
+}
+
+// executable returns the path of name relative to root if it is an
+// executable regular file, once symlinks are resolved inside root.
+func executable(root, name string) (string, error) {
+	target, err := rootfs.Join(root, name)
+	if err != nil {
+		return "", err
+	}
+	info, err := os.Stat(target)
+	if err != nil {
+		return "", err
+	}
+	if !info.Mode().IsRegular() || info.Mode().Perm()&0111 == 0 {
+		return "", fmt.Errorf("%s: not an executable file: %w", name, fs.ErrPermission)
+	}
+	link, err := rootfs.JoinNoFollow(root, name)
+	if err != nil {
+		return "", err
+	}
+	rel, err := filepath.Rel(filepath.Clean(root), link)
+	if err != nil {
+		return "", err
+	}
+	return filepath.ToSlash(rel), nil
+}
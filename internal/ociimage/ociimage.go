@@ -0,0 +1,315 @@
+// Package ociimage reads container images stored as an OCI image layout,
+// either as a directory or as a tar archive such as the output of `docker save`.
+package ociimage
+
+import (
+	"archive/tar"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Media types of the documents found in an image layout.
+const (
+	MediaTypeOCIIndex        = "application/vnd.oci.image.index.v1+json"
+	MediaTypeOCIManifest     = "application/vnd.oci.image.manifest.v1+json"
+	MediaTypeDockerIndex     = "application/vnd.docker.distribution.manifest.list.v2+json"
+	MediaTypeDockerManifest  = "application/vnd.docker.distribution.manifest.v2+json"
+	annotationReferenceType  = "vnd.docker.reference.type"
+	referenceTypeAttestation = "attestation-manifest"
+)
+
+// Descriptor references a blob in the layout.
+type Descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *Platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Index is an OCI image index or Docker manifest list.
+type Index struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Manifests     []Descriptor `json:"manifests"`
+}
+
+// Manifest is an OCI or Docker image manifest.
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType,omitempty"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+}
+
+// Config is the subset of the image configuration used by the checker.
+type Config struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Variant      string `json:"variant,omitempty"`
+	Config       struct {
+		Env        []string `json:"Env,omitempty"`
+		Entrypoint []string `json:"Entrypoint,omitempty"`
+		Cmd        []string `json:"Cmd,omitempty"`
+		WorkingDir string   `json:"WorkingDir,omitempty"`
+		User       string   `json:"User,omitempty"`
+	} `json:"config"`
+}
+
+// Image is a single-platform image found in a layout.
+type Image struct {
+	// Digest is the digest of the image manifest.
+	Digest   string
+	Platform Platform
+	Manifest Manifest
+	Config   Config
+
+	layout *Layout
+}
+
+// Layout is an OCI image layout opened for reading.
+type Layout struct {
+	dir     string
+	cleanup func() error
+}
+
+// legacyManifest is an entry of the manifest.json written by `docker save`.
+type legacyManifest struct {
+	Config string   `json:"Config"`
+	Layers []string `json:"Layers"`
+}
+
+// Open opens the image layout at name. If name is a tar archive, it is
+// extracted into a temporary directory that is removed by Close.
+func Open(name string) (*Layout, error) {
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &Layout{dir: name}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "fips-check-layout-")
+	if err != nil {
+		return nil, err
+	}
+	if err := extractArchive(name, dir); err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("failed to extract image archive: %w", err)
+	}
+	return &Layout{dir: dir, cleanup: func() error { return os.RemoveAll(dir) }}, nil
+}
+
+// Close releases the resources held by the layout.
+func (l *Layout) Close() error {
+	if l.cleanup == nil {
+		return nil
+	}
+	return l.cleanup()
+}
+
+// Images returns every single-platform image referenced by the layout,
+// descending into image indexes and manifest lists. Attestation manifests are skipped.
+func (l *Layout) Images() ([]Image, error) {
+	var index Index
+	err := l.readJSON("index.json", &index)
+	if errors.Is(err, os.ErrNotExist) {
+		return l.legacyImages()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var images []Image
+	if err := l.collect(index.Manifests, &images); err != nil {
+		return nil, err
+	}
+	if len(images) == 0 {
+		return nil, errors.New("image layout does not contain any image manifest")
+	}
+	return images, nil
+}
+
+// collect appends the images referenced by descriptors, following nested indexes.
+func (l *Layout) collect(descriptors []Descriptor, images *[]Image) error {
+	for _, desc := range descriptors {
+		if desc.Annotations[annotationReferenceType] == referenceTypeAttestation {
+			continue
+		}
+
+		switch desc.MediaType {
+		case MediaTypeOCIIndex, MediaTypeDockerIndex:
+			var index Index
+			if err := l.readBlobJSON(desc.Digest, &index); err != nil {
+				return err
+			}
+			if err := l.collect(index.Manifests, images); err != nil {
+				return err
+			}
+		case MediaTypeOCIManifest, MediaTypeDockerManifest:
+			img := Image{Digest: desc.Digest, layout: l}
+			if err := l.readBlobJSON(desc.Digest, &img.Manifest); err != nil {
+				return err
+			}
+			if err := l.readBlobJSON(img.Manifest.Config.Digest, &img.Config); err != nil {
+				return err
+			}
+			img.Platform = Platform{OS: img.Config.OS, Architecture: img.Config.Architecture, Variant: img.Config.Variant}
+			if desc.Platform != nil && desc.Platform.Architecture != "" {
+				img.Platform = *desc.Platform
+			}
+			if img.Platform.OS == "unknown" {
+				// BuildKit stores provenance and SBOMs as unknown/unknown images
+				continue
+			}
+			*images = append(*images, img)
+		}
+	}
+	return nil
+}
+
+// legacyImages reads the manifest.json written by older versions of `docker save`.
+func (l *Layout) legacyImages() ([]Image, error) {
+	var manifests []legacyManifest
+	if err := l.readJSON("manifest.json", &manifests); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, errors.New("not an image layout: neither index.json nor manifest.json found")
+		}
+		return nil, err
+	}
+
+	images := make([]Image, 0, len(manifests))
+	for _, m := range manifests {
+		img := Image{Digest: "sha256:" + strings.TrimSuffix(path.Base(m.Config), ".json"), layout: l}
+		if err := l.readJSON(m.Config, &img.Config); err != nil {
+			return nil, err
+		}
+		img.Platform = Platform{OS: img.Config.OS, Architecture: img.Config.Architecture, Variant: img.Config.Variant}
+		img.Manifest.Config = Descriptor{Digest: img.Digest}
+		for _, layer := range m.Layers {
+			// Legacy layers are referenced by path rather than by digest
+			img.Manifest.Layers = append(img.Manifest.Layers, Descriptor{Digest: "path:" + layer})
+		}
+		images = append(images, img)
+	}
+	return images, nil
+}
+
+// blobPath returns the path of the blob with the given digest.
+func (l *Layout) blobPath(digest string) (string, error) {
+	if rel, ok := strings.CutPrefix(digest, "path:"); ok {
+		return l.path(rel)
+	}
+	algorithm, encoded, ok := strings.Cut(digest, ":")
+	if !ok || algorithm == "" || encoded == "" || strings.ContainsAny(digest, "/\\") {
+		return "", fmt.Errorf("invalid digest %q", digest)
+	}
+	return filepath.Join(l.dir, "blobs", algorithm, encoded), nil
+}
+
+// path returns the path of a file in the layout, rejecting names that escape it.
+func (l *Layout) path(name string) (string, error) {
+	clean := path.Clean("/" + name)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid layout path %q", name)
+	}
+	return filepath.Join(l.dir, filepath.FromSlash(clean)), nil
+}
+
+func (l *Layout) readJSON(name string, v any) error {
+	p, err := l.path(name)
+	if err != nil {
+		return err
+	}
+	return readJSONFile(p, v)
+}
+
+func (l *Layout) readBlobJSON(digest string, v any) error {
+	p, err := l.blobPath(digest)
+	if err != nil {
+		return err
+	}
+	return readJSONFile(p, v)
+}
+
+func readJSONFile(p string, v any) error {
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filepath.Base(p), err)
+	}
+	return nil
+}
+
+// extractArchive extracts the regular files and directories of an image archive.
+func extractArchive(archive, dir string) error {
+	f, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	tr := tar.NewReader(f)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		clean := path.Clean("/" + hdr.Name)
+		if clean == "/" {
+			continue
+		}
+		target := filepath.Join(dir, filepath.FromSlash(clean))
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := writeFile(target, tr, 0o644); err != nil {
+				return err
+			}
+		case tar.TypeSymlink:
+			// Older archives link duplicated layers to a single copy
+			resolved := path.Join(path.Dir(clean), hdr.Linkname)
+			if path.IsAbs(hdr.Linkname) || !strings.HasPrefix(resolved, "/") || resolved == "/" {
+				return fmt.Errorf("archive entry %s links outside the archive", hdr.Name)
+			}
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			if err := os.Symlink(hdr.Linkname, target); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func writeFile(target string, r io.Reader, mode os.FileMode) error {
+	out, err := os.OpenFile(target, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, r); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,331 @@
+package ociimage
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// tarEntry describes a single entry of a test layer.
+type tarEntry struct {
+	name     string
+	typeflag byte
+	body     string
+	linkname string
+}
+
+// layoutBuilder writes an OCI image layout into a temporary directory.
+type layoutBuilder struct {
+	t   *testing.T
+	dir string
+}
+
+func newLayoutBuilder(t *testing.T) *layoutBuilder {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "blobs", "sha256"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return &layoutBuilder{t: t, dir: dir}
+}
+
+func (b *layoutBuilder) blob(mediaType string, data []byte) Descriptor {
+	sum := sha256.Sum256(data)
+	encoded := hex.EncodeToString(sum[:])
+	if err := os.WriteFile(filepath.Join(b.dir, "blobs", "sha256", encoded), data, 0644); err != nil {
+		b.t.Fatal(err)
+	}
+	return Descriptor{MediaType: mediaType, Digest: "sha256:" + encoded, Size: int64(len(data))}
+}
+
+func (b *layoutBuilder) jsonBlob(mediaType string, v any) Descriptor {
+	data, err := json.Marshal(v)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	return b.blob(mediaType, data)
+}
+
+func (b *layoutBuilder) layer(entries ...tarEntry) Descriptor {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for _, e := range entries {
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Mode: 0755}
+		if e.typeflag == tar.TypeReg {
+			hdr.Size = int64(len(e.body))
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			b.t.Fatal(err)
+		}
+		if e.typeflag == tar.TypeReg {
+			if _, err := tw.Write([]byte(e.body)); err != nil {
+				b.t.Fatal(err)
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		b.t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		b.t.Fatal(err)
+	}
+	return b.blob("application/vnd.oci.image.layer.v1.tar+gzip", buf.Bytes())
+}
+
+func (b *layoutBuilder) image(platform Platform, layers ...Descriptor) Descriptor {
+	config := b.jsonBlob("application/vnd.oci.image.config.v1+json", map[string]any{
+		"os":           platform.OS,
+		"architecture": platform.Architecture,
+		"config":       map[string]any{"Entrypoint": []string{"/app"}},
+	})
+	desc := b.jsonBlob(MediaTypeOCIManifest, Manifest{
+		SchemaVersion: 2,
+		MediaType:     MediaTypeOCIManifest,
+		Config:        config,
+		Layers:        layers,
+	})
+	desc.Platform = &platform
+	return desc
+}
+
+func (b *layoutBuilder) index(manifests ...Descriptor) {
+	index := Index{SchemaVersion: 2, MediaType: MediaTypeOCIIndex, Manifests: manifests}
+	data, err := json.Marshal(index)
+	if err != nil {
+		b.t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(b.dir, "index.json"), data, 0644); err != nil {
+		b.t.Fatal(err)
+	}
+}
+
+func TestImagesFromManifestList(t *testing.T) {
+	b := newLayoutBuilder(t)
+	base := b.layer(tarEntry{name: "app", typeflag: tar.TypeReg, body: "binary"})
+	amd64 := b.image(Platform{OS: "linux", Architecture: "amd64"}, base)
+	arm64 := b.image(Platform{OS: "linux", Architecture: "arm64"}, base)
+	attestation := b.image(Platform{OS: "unknown", Architecture: "unknown"})
+	attestation.Annotations = map[string]string{annotationReferenceType: referenceTypeAttestation}
+	list := b.jsonBlob(MediaTypeOCIIndex, Index{SchemaVersion: 2, Manifests: []Descriptor{amd64, arm64, attestation}})
+	b.index(list)
+
+	layout, err := Open(b.dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	defer layout.Close()
+
+	images, err := layout.Images()
+	if err != nil {
+		t.Fatalf("Images failed: %v", err)
+	}
+	if len(images) != 2 {
+		t.Fatalf("Expected 2 images, got %d", len(images))
+	}
+	if images[0].Platform.String() != "linux/amd64" || images[1].Platform.String() != "linux/arm64" {
+		t.Errorf("Unexpected platforms %s, %s", images[0].Platform, images[1].Platform)
+	}
+	if len(images[0].Config.Config.Entrypoint) != 1 || images[0].Config.Config.Entrypoint[0] != "/app" {
+		t.Errorf("Unexpected entrypoint %v", images[0].Config.Config.Entrypoint)
+	}
+}
+
+func TestUnpackAppliesWhiteouts(t *testing.T) {
+	b := newLayoutBuilder(t)
+	lower := b.layer(
+		tarEntry{name: "usr/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/lib/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/lib/libcrypto.so.3", typeflag: tar.TypeReg, body: "lib"},
+		tarEntry{name: "lib", typeflag: tar.TypeSymlink, linkname: "/usr/lib"},
+		tarEntry{name: "etc/", typeflag: tar.TypeDir},
+		tarEntry{name: "etc/removed", typeflag: tar.TypeReg, body: "x"},
+		tarEntry{name: "opt/", typeflag: tar.TypeDir},
+		tarEntry{name: "opt/old", typeflag: tar.TypeReg, body: "x"},
+		tarEntry{name: "escape", typeflag: tar.TypeSymlink, linkname: "/../../.."},
+	)
+	upper := b.layer(
+		tarEntry{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+		tarEntry{name: "opt/", typeflag: tar.TypeDir},
+		tarEntry{name: "opt/new", typeflag: tar.TypeReg, body: "y"},
+		tarEntry{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+		tarEntry{name: "lib/ossl-modules/fips.so", typeflag: tar.TypeReg, body: "fips"},
+		tarEntry{name: "escape/outside", typeflag: tar.TypeReg, body: "z"},
+	)
+	b.index(b.image(Platform{OS: "linux", Architecture: "amd64"}, lower, upper))
+
+	layout, err := Open(b.dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	images, err := layout.Images()
+	if err != nil {
+		t.Fatalf("Images failed: %v", err)
+	}
+
+	dest := t.TempDir()
+	if err := images[0].Unpack(context.Background(), dest); err != nil {
+		t.Fatalf("Unpack failed: %v", err)
+	}
+
+	expectExists := map[string]bool{
+		"usr/lib/libcrypto.so.3":       true,
+		"usr/lib/ossl-modules/fips.so": true,
+		"etc/removed":                  false,
+		"opt/old":                      false,
+		"opt/new":                      true,
+		"outside":                      true,
+	}
+	for name, exists := range expectExists {
+		_, err := os.Stat(filepath.Join(dest, name))
+		if exists && err != nil {
+			t.Errorf("Expected %s to exist: %v", name, err)
+		}
+		if !exists && err == nil {
+			t.Errorf("Expected %s to be removed", name)
+		}
+	}
+}
+
+func TestUnpackLayersReportsChanges(t *testing.T) {
+	b := newLayoutBuilder(t)
+	lower := b.layer(
+		tarEntry{name: "usr/", typeflag: tar.TypeDir},
+		tarEntry{name: "usr/lib/", typeflag: tar.TypeDir},
+		tarEntry{name: "lib", typeflag: tar.TypeSymlink, linkname: "/usr/lib"},
+		tarEntry{name: "etc/", typeflag: tar.TypeDir},
+		tarEntry{name: "etc/removed", typeflag: tar.TypeReg, body: "x"},
+	)
+	upper := b.layer(
+		tarEntry{name: "etc/.wh.removed", typeflag: tar.TypeReg},
+		tarEntry{name: "opt/.wh..wh..opq", typeflag: tar.TypeReg},
+		tarEntry{name: "lib/app", typeflag: tar.TypeReg, body: "app"},
+		tarEntry{name: "lib/app-link", typeflag: tar.TypeLink, linkname: "lib/app"},
+	)
+	b.index(b.image(Platform{OS: "linux", Architecture: "amd64"}, lower, upper))
+
+	layout, err := Open(b.dir)
+	if err != nil {
+		t.Fatalf("Open failed: %v", err)
+	}
+	images, err := layout.Images()
+	if err != nil {
+		t.Fatalf("Images failed: %v", err)
+	}
+
+	var changes []LayerChanges
+	err = images[0].UnpackLayers(context.Background(), t.TempDir(), func(layer Descriptor, c LayerChanges) error {
+		changes = append(changes, c)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("UnpackLayers failed: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Expected changes for 2 layers, got %d", len(changes))
+	}
+	if len(changes[0].Entries) != 5 || len(changes[0].Removed) != 0 {
+		t.Errorf("Expected the 5 entries of the lower layer, got %+v", changes[0])
+	}
+
+	expected := LayerChanges{
+		Removed: []string{"etc/removed"},
+		Cleared: []string{"opt"},
+		Entries: []LayerEntry{
+			{Name: "lib/app", Path: "usr/lib/app", Type: tar.TypeReg},
+			{Name: "lib/app-link", Path: "usr/lib/app-link", Type: tar.TypeLink, Linkname: "usr/lib/app"},
+		},
+	}
+	if !reflect.DeepEqual(changes[1], expected) {
+		t.Errorf("Expected changes %+v, got %+v", expected, changes[1])
+	}
+}
+
+func TestConfigEntrypoint(t *testing.T) {
+	root := t.TempDir()
+	for name, mode := range map[string]os.FileMode{
+		"usr/bin/app":   0755,
+		"usr/bin/data":  0644,
+		"opt/tool/tool": 0755,
+		"srv/run":       0755,
+	} {
+		if err := os.MkdirAll(filepath.Join(root, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(root, name), []byte("x"), mode); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// A merged /usr: /bin is a symlink to usr/bin, and app is linked from /usr/local/bin
+	if err := os.Symlink("usr/bin", filepath.Join(root, "bin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "usr/local/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("/usr/bin/app", filepath.Join(root, "usr/local/bin/app-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		entrypoint []string
+		cmd        []string
+		env        []string
+		workDir    string
+		expected   string
+		err        bool
+	}{
+		{name: "default_path", cmd: []string{"app", "--flag"}, expected: "usr/bin/app"},
+		{name: "symlink_kept", entrypoint: []string{"app-link"}, expected: "usr/local/bin/app-link"},
+		{name: "absolute", entrypoint: []string{"/bin/app"}, cmd: []string{"serve"}, expected: "usr/bin/app"},
+		{name: "image_path", entrypoint: []string{"tool"}, env: []string{"PATH=/opt/tool:/usr/bin"}, expected: "opt/tool/tool"},
+		{name: "image_path_replaces_default", entrypoint: []string{"app"}, env: []string{"PATH=/opt/tool"}, err: true},
+		{name: "working_dir", entrypoint: []string{"./run"}, workDir: "/srv", expected: "srv/run"},
+		{name: "not_executable", cmd: []string{"data"}, err: true},
+		{name: "not_found", cmd: []string{"missing"}, err: true},
+		{name: "no_command", err: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var c Config
+			c.Config.Entrypoint, c.Config.Cmd, c.Config.Env, c.Config.WorkingDir = tt.entrypoint, tt.cmd, tt.env, tt.workDir
+			got, err := c.Entrypoint(root)
+			if tt.err {
+				if err == nil {
+					t.Errorf("Expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Entrypoint failed: %v", err)
+			}
+			if got != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, got)
+			}
+		})
+	}
+}
+
+func TestParsePlatform(t *testing.T) {
+	p, err := ParsePlatform("linux/arm/v7")
+	if err != nil {
+		t.Fatalf("ParsePlatform failed: %v", err)
+	}
+	if !p.Matches(Platform{OS: "linux", Architecture: "arm"}) {
+		t.Error("Expected linux/arm/v7 to match linux/arm")
+	}
+	if (Platform{OS: "linux", Architecture: "arm", Variant: "v6"}).Matches(p) {
+		t.Error("Expected linux/arm/v6 not to match linux/arm/v7")
+	}
+	if _, err := ParsePlatform("linux"); err == nil {
+		t.Error("Expected error for platform without architecture")
+	}
+}
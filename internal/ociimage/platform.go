@@ -0,0 +1,44 @@
+package ociimage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Platform identifies the operating system and CPU architecture an image is built for.
+type Platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+// String formats the platform as os/arch[/variant].
+func (p Platform) String() string {
+	s := p.OS + "/" + p.Architecture
+	if p.Variant != "" {
+		s += "/" + p.Variant
+	}
+	return s
+}
+
+// ParsePlatform parses a platform in the os/arch[/variant] form used by `docker --platform`.
+func ParsePlatform(s string) (Platform, error) {
+	parts := strings.Split(s, "/")
+	if len(parts) < 2 || len(parts) > 3 || parts[0] == "" || parts[1] == "" {
+		return Platform{}, fmt.Errorf("invalid platform %q, expected os/arch[/variant]", s)
+	}
+	p := Platform{OS: parts[0], Architecture: parts[1]}
+	if len(parts) == 3 {
+		p.Variant = parts[2]
+	}
+	return p, nil
+}
+
+// Matches reports whether p satisfies the requested platform.
+// An empty variant in the request matches any variant.
+func (p Platform) Matches(requested Platform) bool {
+	if p.OS != requested.OS || p.Architecture != requested.Architecture {
+		return false
+	}
+	return requested.Variant == "" || p.Variant == requested.Variant
+}
@@ -0,0 +1,255 @@
+package ociimage
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+)
+
+// Whiteout markers defined by the OCI image specification.
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// Unpack applies the layers of the image to dest in order, honoring whiteouts,
+// so that dest ends up containing the image's root filesystem.
+// Device nodes and file ownership are not reproduced.
+func (img Image) Unpack(ctx context.Context, dest string) error {
+	return img.UnpackLayers(ctx, dest, nil)
+}
+
+// LayerChanges describes what applying a layer changed in the root filesystem.
+// Paths are slash-separated and relative to the root, e.g. "usr/bin/app".
+type LayerChanges struct {
+	// Removed lists the paths deleted by whiteouts, including their content
+	Removed []string
+	// Cleared lists the directories whose lower content an opaque whiteout hides
+	Cleared []string
+	// Entries lists the entries the layer wrote, in archive order
+	Entries []LayerEntry
+}
+
+// LayerEntry is an entry written by a layer.
+type LayerEntry struct {
+	// Name is the path of the entry in the layer archive
+	Name string
+	// Path is where the entry was written. It differs from Name if a parent
+	// directory of Name is a symlink, e.g. "usr/lib/x" for "lib/x".
+	Path string
+	// Type is the tar type flag of the entry, e.g. tar.TypeReg
+	Type byte
+	// Linkname is the target of a symlink, as stored in the archive, or
+	// the path of the file a hardlink refers to, resolved like Path
+	Linkname string
+}
+
+// UnpackLayers is like Unpack but calls fn, if not nil, after each layer
+// has been applied. The files the layer wrote can be inspected in dest until
+// fn returns, before later layers replace or remove them.
+func (img Image) UnpackLayers(ctx context.Context, dest string, fn func(Descriptor, LayerChanges) error) error {
+	for _, layer := range img.Manifest.Layers {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		changes, err := img.layout.applyLayer(layer, dest)
+		if err != nil {
+			return fmt.Errorf("failed to apply layer %s: %w", layer.Digest, err)
+		}
+		if fn != nil {
+			if err := fn(layer, changes); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// OpenLayer returns a tar reader over the uncompressed content of a layer.
+// The returned closer must be called once the reader is no longer used.
+func (l *Layout) OpenLayer(layer Descriptor) (*tar.Reader, io.Closer, error) {
+	p, err := l.blobPath(layer.Digest)
+	if err != nil {
+		return nil, nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(f)
+	magic, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			f.Close()
+			return nil, nil, err
+		}
+		return tar.NewReader(gz), f, nil
+	case bytes.Equal(magic, []byte{0x28, 0xb5, 0x2f, 0xfd}):
+		f.Close()
+		return nil, nil, errors.New("zstd compressed layers are not supported")
+	}
+	return tar.NewReader(br), f, nil
+}
+
+// applyLayer extracts a single layer into dest and returns its changes.
+// Whiteouts only affect the lower layers, so they are applied in a first
+// pass before any entry of the layer itself is written.
+func (l *Layout) applyLayer(layer Descriptor, dest string) (LayerChanges, error) {
+	var changes LayerChanges
+	if err := l.forEachEntry(layer, func(hdr *tar.Header, _ io.Reader) error {
+		dir, base := path.Split(path.Clean("/" + hdr.Name))
+		switch {
+		case base == whiteoutOpaque:
+			target, err := rootfs.Join(dest, dir)
+			if err != nil {
+				return err
+			}
+			changes.Cleared = append(changes.Cleared, relPath(dest, target))
+			return clearDir(target)
+		case strings.HasPrefix(base, whiteoutPrefix):
+			target, err := rootfs.JoinNoFollow(dest, path.Join(dir, strings.TrimPrefix(base, whiteoutPrefix)))
+			if err != nil {
+				return err
+			}
+			changes.Removed = append(changes.Removed, relPath(dest, target))
+			return os.RemoveAll(target)
+		}
+		return nil
+	}); err != nil {
+		return changes, err
+	}
+
+	err := l.forEachEntry(layer, func(hdr *tar.Header, r io.Reader) error {
+		name := path.Clean("/" + hdr.Name)
+		if name == "/" || strings.HasPrefix(path.Base(name), whiteoutPrefix) {
+			return nil
+		}
+		entry, err := extractEntry(dest, name, hdr, r)
+		if err != nil {
+			return err
+		}
+		changes.Entries = append(changes.Entries, entry)
+		return nil
+	})
+	return changes, err
+}
+
+// relPath returns target, a path inside dest, as a slash-separated path relative to dest.
+func relPath(dest, target string) string {
+	rel, err := filepath.Rel(dest, target)
+	if err != nil {
+		return filepath.ToSlash(target)
+	}
+	return filepath.ToSlash(rel)
+}
+
+func (l *Layout) forEachEntry(layer Descriptor, fn func(*tar.Header, io.Reader) error) error {
+	tr, closer, err := l.OpenLayer(layer)
+	if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(hdr, tr); err != nil {
+			return err
+		}
+	}
+}
+
+// clearDir removes the content of the directory target, keeping target itself.
+func clearDir(target string) error {
+	entries, err := os.ReadDir(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(target, entry.Name())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractEntry writes a single tar entry to dest and returns where it was
+// written. Parent directories are resolved inside dest so symlinks from lower
+// layers cannot redirect writes to the host filesystem.
+func extractEntry(dest, name string, hdr *tar.Header, r io.Reader) (LayerEntry, error) {
+	parent, err := rootfs.Join(dest, path.Dir(name))
+	if err != nil {
+		return LayerEntry{}, err
+	}
+	target := filepath.Join(parent, path.Base(name))
+	mode := fs.FileMode(hdr.Mode).Perm()
+	entry := LayerEntry{
+		Name: strings.TrimPrefix(name, "/"),
+		Path: relPath(dest, target),
+		Type: hdr.Typeflag,
+	}
+
+	if hdr.Typeflag != tar.TypeDir {
+		if err := os.MkdirAll(parent, 0o755); err != nil {
+			return entry, err
+		}
+		// Later layers replace whatever lower layers put at the same path
+		if err := os.RemoveAll(target); err != nil {
+			return entry, err
+		}
+	}
+
+	switch hdr.Typeflag {
+	case tar.TypeDir:
+		if info, err := os.Lstat(target); err == nil && !info.IsDir() {
+			if err := os.RemoveAll(target); err != nil {
+				return entry, err
+			}
+		}
+		if err := os.MkdirAll(target, 0o755); err != nil {
+			return entry, err
+		}
+		// Keep directories writable so that later layers can be applied
+		return entry, os.Chmod(target, mode|0o700)
+	case tar.TypeReg:
+		if err := writeFile(target, r, 0o600); err != nil {
+			return entry, err
+		}
+		return entry, os.Chmod(target, mode)
+	case tar.TypeSymlink:
+		entry.Linkname = hdr.Linkname
+		return entry, os.Symlink(hdr.Linkname, target)
+	case tar.TypeLink:
+		source, err := rootfs.JoinNoFollow(dest, hdr.Linkname)
+		if err != nil {
+			return entry, err
+		}
+		entry.Linkname = relPath(dest, source)
+		return entry, os.Link(source, target)
+	}
+	// Device nodes and FIFOs are irrelevant for the checks
+	return entry, nil
+}
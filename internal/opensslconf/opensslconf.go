@@ -0,0 +1,234 @@
+// Package opensslconf reads OpenSSL configuration files (openssl.cnf) far
+// enough to answer FIPS questions: which providers are configured, what the
+// default properties are and where the FIPS module configuration lives.
+package opensslconf
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+)
+
+// defaultSection holds the keys that appear before the first section header.
+const defaultSection = "default"
+
+// maxIncludeDepth bounds nested .include directives.
+const maxIncludeDepth = 10
+
+// Config is a parsed OpenSSL configuration, including the files it includes.
+type Config struct {
+	// Files lists the files that were read, the main file first.
+	// Paths are as seen from inside the root the config was loaded from.
+	Files []string
+
+	sections map[string]map[string]string
+	// sectionFiles records which file defined each section.
+	sectionFiles map[string]string
+}
+
+// Load reads the configuration file at name and follows its .include directives.
+// When root is not empty, name and included paths are resolved inside root, as
+// when inspecting a mounted image filesystem; otherwise they refer to the host.
+func Load(root, name string) (*Config, error) {
+	c := &Config{
+		sections:     map[string]map[string]string{},
+		sectionFiles: map[string]string{},
+	}
+	if err := c.load(root, name, 0); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Get returns the value of key in section, or an empty string.
+func (c *Config) Get(section, key string) string {
+	return c.sections[section][key]
+}
+
+// Section returns a copy of the keys and values of a section.
+func (c *Config) Section(name string) map[string]string {
+	section := make(map[string]string, len(c.sections[name]))
+	for k, v := range c.sections[name] {
+		section[k] = v
+	}
+	return section
+}
+
+// Sections returns the names of all sections in sorted order.
+func (c *Config) Sections() []string {
+	names := make([]string, 0, len(c.sections))
+	for name := range c.sections {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// initSection returns the name of the section referenced by openssl_conf.
+func (c *Config) initSection() string {
+	return c.Get(defaultSection, "openssl_conf")
+}
+
+// DefaultProperties returns the default_properties of the algorithm section,
+// e.g. "fips=yes", or an empty string if none are configured.
+func (c *Config) DefaultProperties() string {
+	alg := c.Get(c.initSection(), "alg_section")
+	if alg == "" {
+		return ""
+	}
+	return c.Get(alg, "default_properties")
+}
+
+// Providers returns the provider names configured in the providers section,
+// mapped to the section that configures each provider.
+func (c *Config) Providers() map[string]string {
+	providers := c.Get(c.initSection(), "providers")
+	if providers == "" {
+		return nil
+	}
+	return c.Section(providers)
+}
+
+// ActivatedProviders returns the sorted names of the configured providers
+// whose section activates them, i.e. that OpenSSL loads with its configuration.
+func (c *Config) ActivatedProviders() []string {
+	var names []string
+	for name, section := range c.Providers() {
+		if Activates(c.Get(section, "activate")) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Activates reports whether the value of an activate key activates its
+// provider. OpenSSL accepts 1, yes, true and on.
+func Activates(value string) bool {
+	switch strings.ToLower(value) {
+	case "1", "yes", "true", "on":
+		return true
+	}
+	return false
+}
+
+// FIPSModuleConfig returns the file that configures the fips provider,
+// normally fipsmodule.cnf, or an empty string if the fips provider is not configured.
+func (c *Config) FIPSModuleConfig() string {
+	if section, ok := c.Providers()["fips"]; ok {
+		if file := c.sectionFiles[section]; file != "" {
+			return file
+		}
+	}
+	for _, file := range c.Files {
+		if path.Base(file) == "fipsmodule.cnf" {
+			return file
+		}
+	}
+	return ""
+}
+
+func (c *Config) load(root, name string, depth int) error {
+	if depth > maxIncludeDepth {
+		return fmt.Errorf("%s: includes nested too deeply", name)
+	}
+
+	hostPath, err := resolve(root, name)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(hostPath)
+	if err != nil {
+		return err
+	}
+
+	if info.IsDir() {
+		// Including a directory includes the .cnf and .conf files in it
+		entries, err := os.ReadDir(hostPath)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, entry := range entries {
+			if ext := path.Ext(entry.Name()); !entry.IsDir() && (ext == ".cnf" || ext == ".conf") {
+				names = append(names, entry.Name())
+			}
+		}
+		sort.Strings(names)
+		for _, n := range names {
+			if err := c.load(root, path.Join(name, n), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	f, err := os.Open(hostPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	c.Files = append(c.Files, name)
+
+	section := defaultSection
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, ".include"):
+			include := strings.TrimSpace(strings.TrimPrefix(line, ".include"))
+			include = strings.Trim(strings.TrimSpace(strings.TrimPrefix(include, "=")), `"`)
+			if include == "" {
+				continue
+			}
+			if !path.IsAbs(include) {
+				include = path.Join(path.Dir(name), include)
+			}
+			if err := c.load(root, include, depth+1); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return err
+			}
+		case strings.HasPrefix(line, "."):
+			// Other directives such as .pragma do not affect the values
+		case strings.HasPrefix(line, "["):
+			section = strings.TrimSpace(strings.Trim(line, "[]"))
+			if _, ok := c.sections[section]; !ok {
+				c.sections[section] = map[string]string{}
+				c.sectionFiles[section] = name
+			}
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok {
+				continue
+			}
+			if c.sections[section] == nil {
+				c.sections[section] = map[string]string{}
+				c.sectionFiles[section] = name
+			}
+			c.sections[section][strings.TrimSpace(key)] = strings.Trim(strings.TrimSpace(value), `"`)
+		}
+	}
+	return scanner.Err()
+}
+
+// stripComment removes a trailing # comment.
+func stripComment(line string) string {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		return line[:i]
+	}
+	return line
+}
+
+func resolve(root, name string) (string, error) {
+	if root == "" {
+		return filepath.FromSlash(name), nil
+	}
+	return rootfs.Join(root, name)
+}
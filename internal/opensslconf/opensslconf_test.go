@@ -0,0 +1,88 @@
+package opensslconf
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLoadFollowsIncludes(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "etc/ssl/openssl.cnf"), `
+# FIPS configuration
+openssl_conf = openssl_init
+.include fipsmodule.cnf
+.include = /etc/ssl/conf.d
+.include /etc/ssl/missing.cnf
+
+[openssl_init]
+providers = provider_sect
+alg_section = algorithm_sect
+
+[provider_sect]
+fips = fips_sect
+base = base_sect
+
+[algorithm_sect]
+default_properties = "fips=yes" # trailing comment
+`)
+	writeFile(t, filepath.Join(root, "etc/ssl/fipsmodule.cnf"), `
+[fips_sect]
+activate = 1
+module-mac = 00:11
+`)
+	writeFile(t, filepath.Join(root, "etc/ssl/conf.d/extra.cnf"), `
+[base_sect]
+activate = 1
+`)
+
+	conf, err := Load(root, "/etc/ssl/openssl.cnf")
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if got := conf.DefaultProperties(); got != "fips=yes" {
+		t.Errorf("Expected default properties fips=yes, got %q", got)
+	}
+	if got := conf.FIPSModuleConfig(); got != "/etc/ssl/fipsmodule.cnf" {
+		t.Errorf("Expected fipsmodule.cnf to configure the fips provider, got %q", got)
+	}
+	if got := conf.Get("base_sect", "activate"); got != "1" {
+		t.Errorf("Expected directory include to be read, got activate=%q", got)
+	}
+	if got := conf.ActivatedProviders(); len(got) != 2 || got[0] != "base" || got[1] != "fips" {
+		t.Errorf("Expected the base and fips providers to be activated, got %v", got)
+	}
+	if len(conf.Files) != 3 {
+		t.Errorf("Expected 3 files to be read, got %v", conf.Files)
+	}
+}
+
+func TestLoadWithoutFIPS(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "openssl.cnf")
+	writeFile(t, name, "openssl_conf = openssl_init\n[openssl_init]\nproviders = provider_sect\n[provider_sect]\ndefault = default_sect\n")
+
+	conf, err := Load("", name)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if conf.DefaultProperties() != "" {
+		t.Errorf("Expected no default properties, got %q", conf.DefaultProperties())
+	}
+	if conf.FIPSModuleConfig() != "" {
+		t.Errorf("Expected no FIPS module config, got %q", conf.FIPSModuleConfig())
+	}
+	if got := conf.ActivatedProviders(); len(got) != 0 {
+		t.Errorf("Expected no activated providers, got %v", got)
+	}
+}
@@ -6,12 +6,16 @@
 
 //go:build cgo
 
-// opensslsetup is a package that initializes the OpenSSL library.
-// It doesn't export any symbol, but blank importing it has the
-// side effect of initializing the OpenSSL library.
+// opensslsetup is a package that locates and initializes the OpenSSL library.
+// Unlike the crypto backend it is derived from, it never panics: OpenSSL is
+// loaded explicitly through a Prober, and failures are returned as errors.
 package opensslsetup
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"syscall"
 
 	"github.com/golang-fips/openssl/v2"
@@ -24,45 +28,200 @@ var knownVersions = [...]string{"3", "1.1", "11", "111"}
 
 const lcryptoPrefix = "libcrypto.so."
 
-func init() {
-	lib := library()
+// Candidate is a libcrypto shared library considered while searching the host.
+type Candidate struct {
+	// Name is the library name passed to dlopen, e.g. "libcrypto.so.3"
+	Name string
+	// Exists indicates the library could be loaded
+	Exists bool
+	// FIPS indicates the library runs in FIPS mode by default
+	FIPS bool
+}
+
+// Result is the outcome of probing the host's OpenSSL.
+type Result struct {
+	// Library is the library that was loaded, or attempted to be loaded
+	Library string
+	// Candidates lists every well-known library with its status
+	Candidates     []Candidate
+	OpenSSLVersion string
+	FIPSCapable    bool
+	// Providers describes the OpenSSL 3 provider configuration
+	Providers ProviderDetails
+	// Err is set if OpenSSL could not be loaded
+	Err error
+}
+
+// Prober loads OpenSSL on demand and reports what it found on the host.
+// The zero value searches the well-known library names.
+type Prober struct {
+	// Library selects a specific libcrypto to load, either a library name
+	// such as "libcrypto.so.3" or an absolute path. When empty, the library
+	// is chosen like the Go crypto backend does.
+	Library string
+
+	mu     sync.Mutex
+	probed bool
+	result Result
+}
+
+// Init searches the host for libcrypto and loads the selected library.
+// Only the first call probes the host; later calls return the same result.
+func (p *Prober) Init(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.probed {
+		if err := p.probe(ctx); err != nil {
+			return err
+		}
+	}
+	return p.result.Err
+}
+
+// Reprobe searches the host again and refreshes the reported status.
+// A library that is already loaded stays loaded, as OpenSSL cannot be
+// unloaded from a running process.
+//
+// Probing other libraries temporarily rebinds the OpenSSL functions used by
+// the process, so Reprobe must not run concurrently with other OpenSSL calls.
+func (p *Prober) Reprobe(ctx context.Context) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.probe(ctx); err != nil {
+		return err
+	}
+	return p.result.Err
+}
+
+// Result returns the outcome of the last probe.
+func (p *Prober) Result() Result {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	result := p.result
+	result.Candidates = append([]Candidate(nil), p.result.Candidates...)
+	result.Providers.Providers = append([]Provider(nil), p.result.Providers.Providers...)
+	return result
+}
+
+// probe performs the search and load. It returns an error only if ctx is done.
+func (p *Prober) probe(ctx context.Context) error {
+	candidates, err := searchCandidates(ctx)
+	if err != nil {
+		return err
+	}
+
+	lib := p.Library
+	if lib == "" {
+		lib = library(candidates)
+	}
+
+	result := Result{Library: lib, Candidates: candidates}
+	if err := load(lib); err != nil {
+		result.Err = err
+	} else {
+		result.OpenSSLVersion = openssl.VersionText()
+		result.FIPSCapable = openssl.FIPSCapable()
+		if details, err := inspectProviders(lib); err == nil {
+			result.Providers = details
+		}
+	}
+
+	p.result = result
+	p.probed = true
+	return nil
+}
+
+// loadMu guards the process-wide OpenSSL state shared by all probers.
+var (
+	loadMu  sync.Mutex
+	loaded  string
+	loadErr error
+)
+
+// load initializes OpenSSL from lib. OpenSSL can only be initialized once
+// per process, so loading a different library afterwards fails.
+func load(lib string) error {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	if loaded != "" {
+		if loaded != lib {
+			return fmt.Errorf("opensslcrypto: can't initialize OpenSSL %s: %s is already loaded", lib, loaded)
+		}
+		return loadErr
+	}
+
+	loaded = lib
 	if err := openssl.Init(lib); err != nil {
-		panic("opensslcrypto: can't initialize OpenSSL " + lib + ": " + err.Error())
+		loadErr = fmt.Errorf("opensslcrypto: can't initialize OpenSSL %s: %w", lib, err)
 	}
+	return loadErr
+}
+
+// Loaded returns the library OpenSSL was initialized from, or an error
+// if no library was loaded successfully.
+func Loaded() (string, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	if loaded == "" {
+		return "", errors.New("opensslcrypto: OpenSSL is not initialized")
+	}
+	return loaded, loadErr
 }
 
 // library returns the name of the OpenSSL library to use.
 // It first checks the environment variable GO_OPENSSL_VERSION_OVERRIDE.
-// If that is not set, it searches a well-known list of library names.
+// If that is not set, it picks from the candidates of a search.
 // If no library is found, it returns "libcrypto.so".
-func library() string {
+func library(candidates []Candidate) string {
 	if version, _ := syscall.Getenv("GO_OPENSSL_VERSION_OVERRIDE"); version != "" {
 		return lcryptoPrefix + version
 	}
-	if lib := searchKnownLibrary(); lib != "" {
+	if lib := selectLibrary(candidates); lib != "" {
 		return lib
 	}
 	return lcryptoPrefix[:len(lcryptoPrefix)-1] // no version found, try without version suffix
 }
 
 // checkVersion is a variable that holds the openssl.CheckVersion function.
-// It is initialized in the init function to allow overriding in tests.
+// It is a variable to allow overriding in tests.
 var checkVersion = openssl.CheckVersion
 
-// searchKnownLibrary returns the name of the highest available FIPS-enabled version of OpenSSL
-// using the known library suffixes.
+// searchCandidates checks every known library suffix and reports whether it
+// exists and whether it is FIPS-enabled.
+func searchCandidates(ctx context.Context) ([]Candidate, error) {
+	loadMu.Lock()
+	defer loadMu.Unlock()
+
+	candidates := make([]Candidate, 0, len(knownVersions))
+	for _, v := range knownVersions {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		c := Candidate{Name: lcryptoPrefix + v}
+		c.Exists, c.FIPS = checkVersion(c.Name)
+		candidates = append(candidates, c)
+	}
+	return candidates, nil
+}
+
+// selectLibrary returns the name of the highest available FIPS-enabled version of OpenSSL
+// among the candidates.
 // If no FIPS-enabled version is found, it returns the name of the highest available version.
 // If no version is found, it returns an empty string.
-func searchKnownLibrary() string {
+func selectLibrary(candidates []Candidate) string {
 	var lcryptoFallback string
-	for _, v := range knownVersions {
-		lcryptoCandidate := lcryptoPrefix + v
-		if exists, fips := checkVersion(lcryptoCandidate); exists {
-			if fips {
-				return lcryptoCandidate
+	for _, c := range candidates {
+		if c.Exists {
+			if c.FIPS {
+				return c.Name
 			}
 			if lcryptoFallback == "" {
-				lcryptoFallback = lcryptoCandidate
+				lcryptoFallback = c.Name
 			}
 		}
 	}
@@ -0,0 +1,225 @@
+//go:build cgo
+
+package opensslsetup
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stddef.h>
+#include <stdlib.h>
+#include <string.h>
+
+// Mirrors of the OpenSSL 3 definitions used below, so that the package
+// builds without the OpenSSL development headers.
+typedef struct {
+	const char *key;
+	unsigned int data_type;
+	void *data;
+	size_t data_size;
+	size_t return_size;
+} fc_OSSL_PARAM;
+
+#define FC_OSSL_PARAM_UNSIGNED_INTEGER 2
+#define FC_OSSL_PARAM_UTF8_PTR 6
+#define FC_OSSL_PARAM_UNMODIFIED ((size_t)-1)
+#define FC_OPENSSL_INFO_CONFIG_DIR 1001
+#define FC_OPENSSL_INFO_MODULES_DIR 1003
+#define FC_MAX_PROVIDERS 32
+
+typedef struct {
+	char name[128];
+	char version[64];
+	char buildinfo[256];
+	unsigned int status;
+	int has_status;
+} fc_provider;
+
+typedef struct {
+	int (*do_all)(void *, int (*)(void *, void *), void *);
+	const char *(*get0_name)(const void *);
+	int (*get_params)(const void *, fc_OSSL_PARAM *);
+	int (*is_fips_enabled)(void *);
+	const char *(*info)(int);
+} fc_api;
+
+typedef struct {
+	const fc_api *api;
+	fc_provider *providers;
+	int count;
+} fc_collect_state;
+
+static void fc_copy(char *dst, size_t size, const char *src) {
+	if (src == NULL) {
+		dst[0] = '\0';
+		return;
+	}
+	strncpy(dst, src, size - 1);
+	dst[size - 1] = '\0';
+}
+
+static int fc_collect(void *prov, void *arg) {
+	fc_collect_state *state = arg;
+	if (state->count >= FC_MAX_PROVIDERS) {
+		return 1;
+	}
+	fc_provider *p = &state->providers[state->count++];
+	memset(p, 0, sizeof(*p));
+	fc_copy(p->name, sizeof(p->name), state->api->get0_name(prov));
+
+	const char *version = NULL, *buildinfo = NULL;
+	unsigned int status = 0;
+	fc_OSSL_PARAM params[] = {
+		{"version", FC_OSSL_PARAM_UTF8_PTR, &version, sizeof(version), FC_OSSL_PARAM_UNMODIFIED},
+		{"buildinfo", FC_OSSL_PARAM_UTF8_PTR, &buildinfo, sizeof(buildinfo), FC_OSSL_PARAM_UNMODIFIED},
+		{"status", FC_OSSL_PARAM_UNSIGNED_INTEGER, &status, sizeof(status), FC_OSSL_PARAM_UNMODIFIED},
+		{NULL, 0, NULL, 0, 0},
+	};
+	if (state->api->get_params(prov, params) == 1) {
+		if (params[0].return_size != FC_OSSL_PARAM_UNMODIFIED) {
+			fc_copy(p->version, sizeof(p->version), version);
+		}
+		if (params[1].return_size != FC_OSSL_PARAM_UNMODIFIED) {
+			fc_copy(p->buildinfo, sizeof(p->buildinfo), buildinfo);
+		}
+		if (params[2].return_size != FC_OSSL_PARAM_UNMODIFIED) {
+			p->status = status;
+			p->has_status = 1;
+		}
+	}
+	return 1;
+}
+
+// fc_load resolves the OpenSSL 3 provider API from an already loaded library.
+// It returns 0 if the library is not loaded and -1 if it predates OpenSSL 3.
+static int fc_load(const char *lib, fc_api *api) {
+	void *handle = dlopen(lib, RTLD_LAZY | RTLD_LOCAL | RTLD_NOLOAD);
+	if (handle == NULL) {
+		return 0;
+	}
+	api->do_all = dlsym(handle, "OSSL_PROVIDER_do_all");
+	api->get0_name = dlsym(handle, "OSSL_PROVIDER_get0_name");
+	api->get_params = dlsym(handle, "OSSL_PROVIDER_get_params");
+	api->is_fips_enabled = dlsym(handle, "EVP_default_properties_is_fips_enabled");
+	api->info = dlsym(handle, "OPENSSL_info");
+	if (api->do_all == NULL || api->get0_name == NULL || api->get_params == NULL ||
+		api->is_fips_enabled == NULL || api->info == NULL) {
+		return -1;
+	}
+	return 1;
+}
+
+static int fc_providers(const fc_api *api, fc_provider *providers) {
+	fc_collect_state state = {api, providers, 0};
+	api->do_all(NULL, fc_collect, &state);
+	return state.count;
+}
+
+static int fc_is_fips_enabled(const fc_api *api) {
+	return api->is_fips_enabled(NULL);
+}
+
+static const char *fc_info(const fc_api *api, int type) {
+	return api->info(type);
+}
+*/
+import "C"
+
+import (
+	"errors"
+	"os"
+	"path"
+	"unsafe"
+
+	"github.com/bahe-msft/fips-check/internal/opensslconf"
+)
+
+// Provider describes an activated OpenSSL 3 provider.
+type Provider struct {
+	Name      string
+	Version   string
+	BuildInfo string
+	// Active reports the provider's self-reported status
+	Active bool
+}
+
+// ProviderDetails describes the OpenSSL 3 provider configuration of the loaded library.
+type ProviderDetails struct {
+	// Supported is false for OpenSSL 1.x, which has no providers
+	Supported bool
+	Providers []Provider
+	// FIPSProviderActive reports whether the "fips" provider is activated
+	FIPSProviderActive bool
+	// DefaultPropertiesFIPS reports whether the default properties contain fips=yes
+	DefaultPropertiesFIPS bool
+	// DefaultProperties is the default_properties value from the configuration
+	DefaultProperties string
+	// ConfigDir is OPENSSLDIR as compiled into the library
+	ConfigDir string
+	// ConfigFile is the openssl.cnf in effect, honoring OPENSSL_CONF
+	ConfigFile string
+	// FIPSModuleConfig is the fipsmodule.cnf referenced by the configuration
+	FIPSModuleConfig string
+	// ModulesDir is the provider module directory, honoring OPENSSL_MODULES
+	ModulesDir string
+}
+
+var errNotLoaded = errors.New("opensslcrypto: library is not loaded")
+
+// inspectProviders queries the provider API of lib, which must already be loaded.
+func inspectProviders(lib string) (ProviderDetails, error) {
+	var details ProviderDetails
+
+	clib := C.CString(lib)
+	defer C.free(unsafe.Pointer(clib))
+
+	var api C.fc_api
+	switch C.fc_load(clib, &api) {
+	case 0:
+		return details, errNotLoaded
+	case -1:
+		// OpenSSL 1.x: no providers to report
+		return details, nil
+	}
+	details.Supported = true
+
+	var providers [C.FC_MAX_PROVIDERS]C.fc_provider
+	n := int(C.fc_providers(&api, &providers[0]))
+	for _, p := range providers[:n] {
+		provider := Provider{
+			Name:      C.GoString(&p.name[0]),
+			Version:   C.GoString(&p.version[0]),
+			BuildInfo: C.GoString(&p.buildinfo[0]),
+			Active:    p.has_status == 0 || p.status == 1,
+		}
+		if provider.Name == "fips" && provider.Active {
+			details.FIPSProviderActive = true
+		}
+		details.Providers = append(details.Providers, provider)
+	}
+	details.DefaultPropertiesFIPS = C.fc_is_fips_enabled(&api) == 1
+
+	details.ConfigDir = goStringOrEmpty(C.fc_info(&api, C.FC_OPENSSL_INFO_CONFIG_DIR))
+	details.ModulesDir = goStringOrEmpty(C.fc_info(&api, C.FC_OPENSSL_INFO_MODULES_DIR))
+	if dir := os.Getenv("OPENSSL_MODULES"); dir != "" {
+		details.ModulesDir = dir
+	}
+	details.ConfigFile = os.Getenv("OPENSSL_CONF")
+	if details.ConfigFile == "" && details.ConfigDir != "" {
+		details.ConfigFile = path.Join(details.ConfigDir, "openssl.cnf")
+	}
+
+	if details.ConfigFile != "" {
+		if conf, err := opensslconf.Load("", details.ConfigFile); err == nil {
+			details.DefaultProperties = conf.DefaultProperties()
+			details.FIPSModuleConfig = conf.FIPSModuleConfig()
+		}
+	}
+	return details, nil
+}
+
+func goStringOrEmpty(s *C.char) string {
+	if s == nil {
+		return ""
+	}
+	return C.GoString(s)
+}
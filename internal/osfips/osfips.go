@@ -0,0 +1,146 @@
+// Package osfips detects whether a Linux node runs in FIPS mode from the
+// kernel and operating system configuration. It reads plain files only, so it
+// works on a mounted node filesystem as well as on the running host.
+package osfips
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/rootfs"
+)
+
+// Files inspected for the FIPS mode signals, relative to the host root.
+const (
+	KernelFIPSFile    = "/proc/sys/crypto/fips_enabled"
+	KernelCmdlineFile = "/proc/cmdline"
+	SystemFIPSFile    = "/etc/system-fips"
+	CryptoPolicyFile  = "/etc/crypto-policies/config"
+)
+
+// Signal is a single indicator of FIPS mode.
+type Signal struct {
+	// Source is the file the signal was read from, as seen from the host root
+	Source string
+	// Found indicates the file exists
+	Found bool
+	// Enabled indicates the signal reports FIPS mode
+	Enabled bool
+	// Value is the relevant content, e.g. "1" or the crypto policy name
+	Value string
+	// Err is set if the file exists but could not be read
+	Err error
+}
+
+// Status holds the FIPS mode signals of a node.
+type Status struct {
+	// Root is the host root the files were read from
+	Root string
+	// KernelFIPS is /proc/sys/crypto/fips_enabled
+	KernelFIPS Signal
+	// KernelCmdline is the fips=1 flag on the kernel command line
+	KernelCmdline Signal
+	// SystemFIPS is the /etc/system-fips marker used by RHEL 8 and derivatives
+	SystemFIPS Signal
+	// CryptoPolicy is the system-wide crypto policy, enabled for FIPS and its subpolicies
+	CryptoPolicy Signal
+}
+
+// Enabled reports whether the kernel runs in FIPS mode.
+func (s Status) Enabled() bool {
+	return s.KernelFIPS.Enabled
+}
+
+// Detect reads the FIPS mode signals below root. An empty root means "/".
+func Detect(root string) Status {
+	if root == "" {
+		root = "/"
+	}
+	return Status{
+		Root:          root,
+		KernelFIPS:    kernelFIPS(root),
+		KernelCmdline: kernelCmdline(root),
+		SystemFIPS:    systemFIPS(root),
+		CryptoPolicy:  cryptoPolicy(root),
+	}
+}
+
+func kernelFIPS(root string) Signal {
+	s, content := read(root, KernelFIPSFile)
+	if s.Found && s.Err == nil {
+		s.Value = strings.TrimSpace(content)
+		s.Enabled = s.Value == "1"
+	}
+	return s
+}
+
+func kernelCmdline(root string) Signal {
+	s, content := read(root, KernelCmdlineFile)
+	if s.Found && s.Err == nil {
+		for _, arg := range strings.Fields(content) {
+			if value, ok := strings.CutPrefix(arg, "fips="); ok {
+				s.Value = value
+				s.Enabled = value == "1"
+			}
+		}
+	}
+	return s
+}
+
+func systemFIPS(root string) Signal {
+	s := Signal{Source: SystemFIPSFile}
+	name, err := rootfs.Join(root, SystemFIPSFile)
+	if err != nil {
+		s.Err = err
+		return s
+	}
+	if _, err := os.Stat(name); err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.Err = err
+		}
+		return s
+	}
+	s.Found = true
+	s.Enabled = true
+	return s
+}
+
+func cryptoPolicy(root string) Signal {
+	s, content := read(root, CryptoPolicyFile)
+	if s.Found && s.Err == nil {
+		for _, line := range strings.Split(content, "\n") {
+			line = strings.TrimSpace(line)
+			if line == "" || strings.HasPrefix(line, "#") {
+				continue
+			}
+			s.Value = line
+			break
+		}
+		// Subpolicies are appended with a colon, e.g. "FIPS:OSPP"
+		policy, _, _ := strings.Cut(s.Value, ":")
+		s.Enabled = policy == "FIPS"
+	}
+	return s
+}
+
+// read returns the content of name below root and a signal recording its source.
+func read(root, name string) (Signal, string) {
+	s := Signal{Source: name}
+	hostPath, err := rootfs.Join(root, name)
+	if err != nil {
+		s.Err = err
+		return s, ""
+	}
+	data, err := os.ReadFile(hostPath)
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			s.Found = true
+			s.Err = err
+		}
+		return s, ""
+	}
+	s.Found = true
+	return s, string(data)
+}
@@ -0,0 +1,68 @@
+package osfips
+
+import (
+	"testing"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected Status
+	}{
+		{
+			name:  "empty_root",
+			files: nil,
+		},
+		{
+			name: "fips_mode",
+			files: map[string]string{
+				"proc/sys/crypto/fips_enabled": "1\n",
+				"proc/cmdline":                 "ro fips=1 boot=UUID=1234\n",
+				"etc/system-fips":              "",
+				"etc/crypto-policies/config":   "FIPS\n",
+			},
+			expected: Status{
+				KernelFIPS:    Signal{Found: true, Enabled: true, Value: "1"},
+				KernelCmdline: Signal{Found: true, Enabled: true, Value: "1"},
+				SystemFIPS:    Signal{Found: true, Enabled: true},
+				CryptoPolicy:  Signal{Found: true, Enabled: true, Value: "FIPS"},
+			},
+		},
+		{
+			name: "fips_disabled",
+			files: map[string]string{
+				"proc/sys/crypto/fips_enabled": "0\n",
+				"proc/cmdline":                 "ro fips=0\n",
+				"etc/crypto-policies/config":   "DEFAULT:NO-SHA1\n",
+			},
+			expected: Status{
+				KernelFIPS:    Signal{Found: true, Value: "0"},
+				KernelCmdline: Signal{Found: true, Value: "0"},
+				CryptoPolicy:  Signal{Found: true, Value: "DEFAULT:NO-SHA1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			testutil.WriteFiles(t, root, tt.files)
+
+			status := Detect(root)
+			tt.expected.Root = root
+			tt.expected.KernelFIPS.Source = KernelFIPSFile
+			tt.expected.KernelCmdline.Source = KernelCmdlineFile
+			tt.expected.SystemFIPS.Source = SystemFIPSFile
+			tt.expected.CryptoPolicy.Source = CryptoPolicyFile
+			if status != tt.expected {
+				t.Errorf("Detect() = %+v, expected %+v", status, tt.expected)
+			}
+			if status.Enabled() != tt.expected.KernelFIPS.Enabled {
+				t.Errorf("Enabled() = %t", status.Enabled())
+			}
+		})
+	}
+}
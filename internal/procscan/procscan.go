@@ -0,0 +1,277 @@
+// Package procscan checks the running processes of a Linux node for FIPS
+// compliance through /proc. Unlike a filesystem scan, which skips /proc,
+// /proc/<pid>/exe reaches the executable of every process, whatever
+// container or mount namespace it runs in and even if it was deleted, and
+// /proc/<pid>/maps and /proc/<pid>/environ tell which libcrypto the process
+// actually loaded and whether it was started with GOFIPS.
+package procscan
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/binarychecker"
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// Process is a running process and the static analysis of its executable.
+type Process struct {
+	PID int
+	// Exe is the path of the executable as seen by the process, with a
+	// " (deleted)" suffix if it was removed since the process started
+	Exe string
+	// Cgroup is the cgroup of the process, from the unified hierarchy if there is one
+	Cgroup string
+	// ContainerID is the ID of the container the process runs in, taken from
+	// its cgroup, e.g. by containerd, CRI-O or Docker
+	ContainerID string
+	// Libcrypto lists the libcrypto libraries mapped into the process
+	Libcrypto []string
+	// FIPSProviders lists the OpenSSL FIPS provider modules mapped into the
+	// process, such as ossl-modules/fips.so or symcryptprovider.so
+	FIPSProviders []string
+	// GOFIPS is the value of GOFIPS in the environment the process was started with
+	GOFIPS    string
+	GOFIPSSet bool
+	// IsGo indicates the executable is a Go binary, analyzed into GoBinaryDetails
+	IsGo            bool
+	GoBinaryDetails report.GoBinaryReportDetails
+	// SameExecutableAs is the PID of an earlier process running the same
+	// executable, whose analysis this process reuses
+	SameExecutableAs int
+	// Err is set if the process could not be fully inspected, e.g. for lack
+	// of permission to read the files of another user's process
+	Err error
+}
+
+// FIPSMode reports whether a Go systemcrypto binary runs OpenSSL in FIPS
+// mode: GOFIPS=1 requests it, and without GOFIPS it follows the kernel.
+func (p Process) FIPSMode(kernelFIPS bool) bool {
+	if p.GOFIPSSet {
+		return p.GOFIPS == "1"
+	}
+	return kernelFIPS
+}
+
+// Status is the verdict of Process.Check.
+type Status int
+
+const (
+	// StatusCompliant means the process runs Go systemcrypto in FIPS mode.
+	StatusCompliant Status = iota
+	// StatusNotCompliant means the process does not run in FIPS mode.
+	StatusNotCompliant
+	// StatusInconclusive means the process could not be fully inspected, so
+	// what it loaded or the environment it was started with is unknown.
+	StatusInconclusive
+)
+
+// Check judges whether the Go process p is FIPS compliant on a node whose
+// kernel runs in FIPS mode if kernelFIPS is set. reason explains a verdict
+// other than StatusCompliant.
+func (p Process) Check(kernelFIPS bool) (status Status, reason string) {
+	details := p.GoBinaryDetails
+	switch {
+	case p.Err != nil:
+		return StatusInconclusive, fmt.Sprintf("could not be inspected: %v", p.Err)
+	case !details.UseSystemcrypto:
+		return StatusNotCompliant, "systemcrypto not in use"
+	case !details.CGOEnabled:
+		return StatusNotCompliant, "built without cgo, so OpenSSL cannot be loaded"
+	case len(p.Libcrypto) == 0:
+		return StatusNotCompliant, "no libcrypto loaded"
+	case !p.FIPSMode(kernelFIPS) && p.GOFIPSSet:
+		return StatusNotCompliant, fmt.Sprintf("not in FIPS mode: GOFIPS=%s", p.GOFIPS)
+	case !p.FIPSMode(kernelFIPS):
+		return StatusNotCompliant, "not in FIPS mode: GOFIPS is not set and the kernel is not in FIPS mode"
+	}
+	return StatusCompliant, ""
+}
+
+// setErr records err as the error of p, unless an earlier one was recorded.
+func (p *Process) setErr(err error) {
+	if p.Err == nil {
+		p.Err = err
+	}
+}
+
+// Scan lists the processes in procDir, usually "/proc", in PID order.
+// Kernel threads, which have no executable, and processes that exit during
+// the scan are left out. Each unique executable is analyzed once.
+func Scan(ctx context.Context, procDir string) ([]Process, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+	var pids []int
+	for _, entry := range entries {
+		if pid, err := strconv.Atoi(entry.Name()); err == nil && pid > 0 {
+			pids = append(pids, pid)
+		}
+	}
+	slices.Sort(pids)
+
+	var processes []Process
+	var executables []executable
+	for _, pid := range pids {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		p, ok := inspect(filepath.Join(procDir, strconv.Itoa(pid)), pid)
+		if !ok {
+			continue
+		}
+		if p.Exe != "" {
+			executables = analyze(ctx, filepath.Join(procDir, strconv.Itoa(pid), "exe"), &p, executables)
+		}
+		processes = append(processes, p)
+	}
+	return processes, nil
+}
+
+// executable is an executable analyzed for an earlier process.
+type executable struct {
+	info    fs.FileInfo
+	pid     int
+	isGo    bool
+	details report.GoBinaryReportDetails
+}
+
+// inspect reads the /proc files of the process pid in dir. It returns false
+// for kernel threads and processes that are gone.
+func inspect(dir string, pid int) (Process, bool) {
+	p := Process{PID: pid}
+	exe, err := os.Readlink(filepath.Join(dir, "exe"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return p, false
+	}
+	if err != nil {
+		p.Err = err
+		return p, true
+	}
+	p.Exe = exe
+	if cgroup, err := os.ReadFile(filepath.Join(dir, "cgroup")); err == nil {
+		p.Cgroup, p.ContainerID = parseCgroup(string(cgroup))
+	} else {
+		p.setErr(err)
+	}
+	if maps, err := os.ReadFile(filepath.Join(dir, "maps")); err == nil {
+		p.Libcrypto, p.FIPSProviders = parseMaps(maps)
+	} else {
+		p.setErr(err)
+	}
+	if environ, err := os.ReadFile(filepath.Join(dir, "environ")); err == nil {
+		p.GOFIPS, p.GOFIPSSet = getenv(environ, "GOFIPS")
+	} else {
+		p.setErr(err)
+	}
+	return p, true
+}
+
+// analyze fills in the analysis of the executable exe of p, reusing that of
+// an earlier process in executables, and returns executables with the new
+// one added.
+func analyze(ctx context.Context, exe string, p *Process, executables []executable) []executable {
+	f, err := os.Open(exe)
+	if err != nil {
+		p.setErr(err)
+		return executables
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		p.setErr(err)
+		return executables
+	}
+	for _, e := range executables {
+		if os.SameFile(e.info, info) {
+			p.IsGo, p.GoBinaryDetails, p.SameExecutableAs = e.isGo, e.details, e.pid
+			return executables
+		}
+	}
+
+	details, err := binarychecker.Analyze(ctx, f, info.Size())
+	switch {
+	case err == nil:
+		p.IsGo, p.GoBinaryDetails = true, details
+	case errors.Is(err, report.ErrNotELF), errors.Is(err, report.ErrNoBuildInfo):
+	default:
+		p.setErr(err)
+		return executables
+	}
+	return append(executables, executable{info: info, pid: p.PID, isGo: p.IsGo, details: p.GoBinaryDetails})
+}
+
+// containerIDPattern matches the 64 hex digit container IDs that container
+// runtimes put in cgroup names, e.g. "cri-containerd-<id>.scope" or "/docker/<id>".
+var containerIDPattern = regexp.MustCompile(`[0-9a-f]{64}`)
+
+// parseCgroup returns the cgroup of a process from the content of
+// /proc/<pid>/cgroup, preferring the unified hierarchy, and the ID of the
+// container it runs in, if any.
+func parseCgroup(content string) (cgroup, containerID string) {
+	for line := range strings.Lines(content) {
+		// Lines are hierarchy-ID:controllers:path, and 0::path is the unified hierarchy
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		if cgroup == "" || (parts[0] == "0" && parts[1] == "") {
+			cgroup = parts[2]
+		}
+		if containerID == "" {
+			if ids := containerIDPattern.FindAllString(parts[2], -1); len(ids) > 0 {
+				containerID = ids[len(ids)-1]
+			}
+		}
+	}
+	return cgroup, containerID
+}
+
+// parseMaps returns the libcrypto libraries and FIPS provider modules mapped
+// into a process, from the content of /proc/<pid>/maps.
+func parseMaps(maps []byte) (libcrypto, providers []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(maps))
+	for scanner.Scan() {
+		// Lines are address perms offset dev inode [pathname], and the pathname may contain spaces
+		fields := strings.SplitN(scanner.Text(), " ", 6)
+		if len(fields) != 6 {
+			continue
+		}
+		name := strings.TrimSuffix(strings.TrimLeft(fields[5], " "), " (deleted)")
+		if !strings.HasPrefix(name, "/") {
+			continue
+		}
+		kind, _ := cryptoinventory.Classify(name)
+		_, provider := cmvp.FileModule(name)
+		switch {
+		case kind == cryptoinventory.KindLibcrypto && !slices.Contains(libcrypto, name):
+			libcrypto = append(libcrypto, name)
+		case provider && !slices.Contains(providers, name):
+			providers = append(providers, name)
+		}
+	}
+	return libcrypto, providers
+}
+
+// getenv returns the value of key in the NUL-separated content of /proc/<pid>/environ.
+func getenv(environ []byte, key string) (string, bool) {
+	for kv := range bytes.SplitSeq(environ, []byte{0}) {
+		if k, v, ok := bytes.Cut(kv, []byte("=")); ok && string(k) == key {
+			return string(v), true
+		}
+	}
+	return "", false
+}
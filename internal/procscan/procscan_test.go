@@ -0,0 +1,182 @@
+package procscan
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+const containerID = "4f2a9c1e8b7d6a5f4e3d2c1b0a9f8e7d6c5b4a3f2e1d0c9b8a7f6e5d4c3b2a1f"
+
+func TestScan(t *testing.T) {
+	bin := t.TempDir()
+	testutil.WriteTestBinaries(t, bin, "app")
+	goBinary := filepath.Join(bin, "app")
+	script := filepath.Join(bin, "script")
+	if err := os.WriteFile(script, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	proc := t.TempDir()
+	process := func(pid, exe string, files map[string]string) {
+		dir := filepath.Join(proc, pid)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if exe != "" {
+			if err := os.Symlink(exe, filepath.Join(dir, "exe")); err != nil {
+				t.Fatal(err)
+			}
+		}
+		for name, content := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	process("12", goBinary, map[string]string{
+		"cgroup": "0::/kubepods.slice/kubepods-pod1.slice/cri-containerd-" + containerID + ".scope\n",
+		"maps": "55d0c0a00000-55d0c0a01000 r--p 00000000 08:01 1234   " + goBinary + "\n" +
+			"7f1c2a000000-7f1c2a100000 r-xp 00000000 08:01 5678   /usr/lib64/libcrypto.so.3\n" +
+			"7f1c2a100000-7f1c2a200000 r--p 00100000 08:01 5678   /usr/lib64/libcrypto.so.3\n" +
+			"7f1c2b000000-7f1c2b100000 r-xp 00000000 08:01 9012   /usr/lib64/ossl-modules/fips.so\n" +
+			"7ffd5a000000-7ffd5a021000 rw-p 00000000 00:00 0      [stack]\n",
+		"environ": "PATH=/usr/bin\x00GOFIPS=1\x00HOME=/\x00",
+	})
+	process("3", goBinary, map[string]string{"cgroup": "0::/system.slice/agent.service\n", "maps": "", "environ": ""})
+	process("7", script, map[string]string{"cgroup": "0::/\n", "maps": "", "environ": ""})
+	// Kernel threads have no executable
+	process("2", "", map[string]string{"cgroup": "0::/\n"})
+	if err := os.Symlink("12", filepath.Join(proc, "self")); err != nil {
+		t.Fatal(err)
+	}
+
+	processes, err := Scan(context.Background(), proc)
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+	var pids []int
+	for _, p := range processes {
+		pids = append(pids, p.PID)
+		if p.Err != nil {
+			t.Errorf("PID %d: unexpected error %v", p.PID, p.Err)
+		}
+	}
+	if !slices.Equal(pids, []int{3, 7, 12}) {
+		t.Fatalf("Expected PIDs [3 7 12], got %v", pids)
+	}
+
+	agent, script7, app := processes[0], processes[1], processes[2]
+	if !agent.IsGo || agent.GoBinaryDetails.GoVersion == "" || agent.SameExecutableAs != 0 {
+		t.Errorf("Expected PID 3 to be an analyzed Go process, got %+v", agent)
+	}
+	if script7.IsGo {
+		t.Errorf("Expected PID 7 not to be a Go process")
+	}
+	// PID 12 runs the same executable as PID 3, which was analyzed first
+	if !app.IsGo || app.SameExecutableAs != 3 || app.GoBinaryDetails != agent.GoBinaryDetails {
+		t.Errorf("Expected PID 12 to reuse the analysis of PID 3, got %+v", app)
+	}
+	if app.Exe != goBinary || app.ContainerID != containerID {
+		t.Errorf("Unexpected exe %q or container ID %q", app.Exe, app.ContainerID)
+	}
+	if !slices.Equal(app.Libcrypto, []string{"/usr/lib64/libcrypto.so.3"}) ||
+		!slices.Equal(app.FIPSProviders, []string{"/usr/lib64/ossl-modules/fips.so"}) {
+		t.Errorf("Unexpected libcrypto %v or providers %v", app.Libcrypto, app.FIPSProviders)
+	}
+	if !app.GOFIPSSet || app.GOFIPS != "1" || agent.GOFIPSSet {
+		t.Errorf("Expected GOFIPS=1 for PID 12 only, got %q, %q", app.GOFIPS, agent.GOFIPS)
+	}
+}
+
+func TestParseCgroup(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		cgroup      string
+		containerID string
+	}{
+		{
+			name:    "unified",
+			content: "0::/system.slice/containerd.service\n",
+			cgroup:  "/system.slice/containerd.service",
+		},
+		{
+			name:        "v1_docker",
+			content:     "12:cpu,cpuacct:/docker/" + containerID + "\n1:name=systemd:/docker/" + containerID + "\n",
+			cgroup:      "/docker/" + containerID,
+			containerID: containerID,
+		},
+		{
+			name:        "hybrid_prefers_unified",
+			content:     "1:name=systemd:/user.slice\n0::/kubepods/burstable/pod1/crio-" + containerID + ".scope\n",
+			cgroup:      "/kubepods/burstable/pod1/crio-" + containerID + ".scope",
+			containerID: containerID,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cgroup, id := parseCgroup(tt.content)
+			if cgroup != tt.cgroup || id != tt.containerID {
+				t.Errorf("Expected %q, %q, got %q, %q", tt.cgroup, tt.containerID, cgroup, id)
+			}
+		})
+	}
+}
+
+func TestProcessCheck(t *testing.T) {
+	good := report.GoBinaryReportDetails{UseSystemcrypto: true, CGOEnabled: true}
+	libcrypto := []string{"/usr/lib64/libcrypto.so.3"}
+
+	tests := []struct {
+		name       string
+		process    Process
+		kernelFIPS bool
+		// status defaults to StatusCompliant without a reason and to
+		// StatusNotCompliant with one
+		status Status
+		reason string
+	}{
+		{name: "gofips", process: Process{GoBinaryDetails: good, Libcrypto: libcrypto, GOFIPS: "1", GOFIPSSet: true}},
+		{name: "kernel_fips", process: Process{GoBinaryDetails: good, Libcrypto: libcrypto}, kernelFIPS: true},
+		{
+			name:       "gofips_disabled",
+			process:    Process{GoBinaryDetails: good, Libcrypto: libcrypto, GOFIPS: "0", GOFIPSSet: true},
+			kernelFIPS: true,
+			reason:     "not in FIPS mode: GOFIPS=0",
+		},
+		{
+			name:    "no_fips_mode",
+			process: Process{GoBinaryDetails: good, Libcrypto: libcrypto},
+			reason:  "not in FIPS mode: GOFIPS is not set and the kernel is not in FIPS mode",
+		},
+		{name: "no_libcrypto", process: Process{GoBinaryDetails: good, GOFIPS: "1", GOFIPSSet: true}, reason: "no libcrypto loaded"},
+		{name: "not_systemcrypto", process: Process{Libcrypto: libcrypto}, kernelFIPS: true, reason: "systemcrypto not in use"},
+		{
+			// Without /proc/<pid>/maps, no libcrypto is known to be loaded
+			name:       "unreadable_maps",
+			process:    Process{GoBinaryDetails: good, GOFIPS: "1", GOFIPSSet: true, Err: fs.ErrPermission},
+			kernelFIPS: true,
+			status:     StatusInconclusive,
+			reason:     "could not be inspected: permission denied",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := tt.status
+			if want == StatusCompliant && tt.reason != "" {
+				want = StatusNotCompliant
+			}
+			status, reason := tt.process.Check(tt.kernelFIPS)
+			if status != want || reason != tt.reason {
+				t.Errorf("Expected %d, %q, got %d, %q", want, tt.reason, status, reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+// Package rootfs resolves paths inside a root filesystem tree, such as an
+// unpacked container image, without ever escaping to the host filesystem.
+package rootfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// maxSymlinks bounds the number of symlinks followed while resolving a single path.
+const maxSymlinks = 255
+
+// ErrTooManySymlinks is returned when resolving a path follows more than maxSymlinks links.
+var ErrTooManySymlinks = errors.New("too many levels of symbolic links")
+
+// Join resolves name inside root the way a process chrooted to root would:
+// ".." never climbs above root and symlinks, including absolute ones, are
+// interpreted relative to root. Components that do not exist are kept literally.
+// The final component is followed if it is a symlink.
+func Join(root, name string) (string, error) {
+	rel, err := resolve(root, name, true)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// JoinNoFollow is like Join but does not follow the final component, so the
+// result names a symlink itself rather than its target.
+func JoinNoFollow(root, name string) (string, error) {
+	rel, err := resolve(root, name, false)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(root, filepath.FromSlash(rel)), nil
+}
+
+// Rel resolves name inside root like Join and returns the result as a
+// slash-separated path relative to root. The root itself is returned as ".".
+func Rel(root, name string) (string, error) {
+	rel, err := resolve(root, name, true)
+	if err != nil {
+		return "", err
+	}
+	if rel == "" {
+		return ".", nil
+	}
+	return rel, nil
+}
+
+// resolve returns the slash-separated path of name relative to root, with
+// symlinks resolved inside root. The empty string denotes root.
+func resolve(root, name string, followFinal bool) (string, error) {
+	root = filepath.Clean(root)
+	resolved := ""
+	remaining := filepath.ToSlash(name)
+	links := 0
+
+	for remaining != "" {
+		var part string
+		part, remaining, _ = strings.Cut(remaining, "/")
+
+		switch part {
+		case "", ".":
+			continue
+		case "..":
+			if i := strings.LastIndex(resolved, "/"); i >= 0 {
+				resolved = resolved[:i]
+			} else {
+				resolved = ""
+			}
+			continue
+		}
+
+		next := path.Join(resolved, part)
+		if remaining == "" && !followFinal {
+			resolved = next
+			break
+		}
+
+		info, err := os.Lstat(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				resolved = next
+				continue
+			}
+			return "", err
+		}
+		if info.Mode()&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		links++
+		if links > maxSymlinks {
+			return "", fmt.Errorf("resolving %s: %w", name, ErrTooManySymlinks)
+		}
+		target, err := os.Readlink(filepath.Join(root, filepath.FromSlash(next)))
+		if err != nil {
+			return "", err
+		}
+		target = filepath.ToSlash(target)
+		if path.IsAbs(target) {
+			resolved = ""
+		}
+		remaining = target + "/" + remaining
+	}
+
+	return resolved, nil
+}
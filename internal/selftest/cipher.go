@@ -0,0 +1,73 @@
+//go:build cgo
+
+package selftest
+
+/*
+#cgo LDFLAGS: -ldl
+#include <dlfcn.h>
+#include <stddef.h>
+#include <stdlib.h>
+
+typedef struct {
+	const void *(*get_cipherbyname)(const char *);
+	void *(*ctx_new)(void);
+	void (*ctx_free)(void *);
+	int (*encrypt_init)(void *, const void *, void *, const unsigned char *, const unsigned char *);
+} fc_cipher_api;
+
+// fc_cipher_accepted reports whether the named cipher can be initialized for
+// encryption with the default properties of the already loaded library.
+// It returns 1 if accepted, 0 if rejected and -1 if the API is unavailable.
+static int fc_cipher_accepted(const char *lib, const char *name) {
+	void *handle = dlopen(lib, RTLD_LAZY | RTLD_LOCAL | RTLD_NOLOAD);
+	if (handle == NULL) {
+		return -1;
+	}
+	fc_cipher_api api;
+	api.get_cipherbyname = dlsym(handle, "EVP_get_cipherbyname");
+	api.ctx_new = dlsym(handle, "EVP_CIPHER_CTX_new");
+	api.ctx_free = dlsym(handle, "EVP_CIPHER_CTX_free");
+	api.encrypt_init = dlsym(handle, "EVP_EncryptInit_ex");
+	if (api.get_cipherbyname == NULL || api.ctx_new == NULL || api.ctx_free == NULL || api.encrypt_init == NULL) {
+		return -1;
+	}
+
+	const void *cipher = api.get_cipherbyname(name);
+	if (cipher == NULL) {
+		return 0;
+	}
+	void *ctx = api.ctx_new();
+	if (ctx == NULL) {
+		return -1;
+	}
+	unsigned char key[32] = {0}, iv[12] = {0};
+	int ok = api.encrypt_init(ctx, cipher, NULL, key, iv);
+	api.ctx_free(ctx);
+	return ok == 1;
+}
+*/
+import "C"
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+// cipherAccepted reports whether lib allows the named cipher to be used.
+// golang-fips/openssl has no API for ciphers such as ChaCha20, so they are
+// initialized through the EVP interface of the already loaded library.
+func cipherAccepted(lib, name string) (bool, error) {
+	clib := C.CString(lib)
+	defer C.free(unsafe.Pointer(clib))
+	cname := C.CString(name)
+	defer C.free(unsafe.Pointer(cname))
+
+	switch C.fc_cipher_accepted(clib, cname) {
+	case 1:
+		return true, nil
+	case 0:
+		return false, nil
+	default:
+		return false, fmt.Errorf("cannot initialize %s through %s", name, lib)
+	}
+}
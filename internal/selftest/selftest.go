@@ -0,0 +1,443 @@
+//go:build cgo
+
+// Package selftest runs cryptographic known-answer tests through the OpenSSL
+// library loaded by opensslsetup. Approved algorithms must reproduce their
+// test vectors in FIPS mode, and non-approved algorithms must be rejected.
+package selftest
+
+import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/cipher"
+	"encoding/asn1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/opensslsetup"
+	"github.com/golang-fips/openssl/v2"
+	"github.com/golang-fips/openssl/v2/bbig"
+)
+
+// Result is the outcome of a single self-test.
+type Result struct {
+	// Name identifies the test, e.g. "AES-128-GCM"
+	Name string
+	// Approved is true for FIPS-approved algorithms, which must work.
+	// Non-approved algorithms pass when they are rejected.
+	Approved bool
+	Passed   bool
+	Duration time.Duration
+	// Err explains why the test failed
+	Err error
+}
+
+// Report holds the results of a self-test run.
+type Report struct {
+	// Library is the libcrypto the tests ran against
+	Library string
+	// FIPSMode indicates the tests ran with FIPS mode enabled
+	FIPSMode bool
+	// FIPSModeErr is set if FIPS mode could not be enabled
+	FIPSModeErr error
+	// FIPSRestoreErr is set if FIPS mode, enabled for the tests, could not
+	// be disabled again afterwards, leaving the process in FIPS mode
+	FIPSRestoreErr error
+	Results        []Result
+}
+
+// Passed reports whether every test passed in FIPS mode.
+func (r Report) Passed() bool {
+	if !r.FIPSMode {
+		return false
+	}
+	for _, result := range r.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// test is a self-test. Approved tests return an error if the algorithm
+// misbehaves; non-approved tests return nil if the algorithm is rejected.
+type test struct {
+	name     string
+	approved bool
+	run      func() error
+}
+
+var tests = []test{
+	{"SHA-256", true, testSHA256},
+	{"SHA-384", true, testSHA384},
+	{"SHA-512", true, testSHA512},
+	{"HMAC-SHA-256", true, testHMAC},
+	{"AES-128-GCM", true, testAESGCM},
+	{"HKDF-SHA-256", true, testHKDF},
+	{"ECDSA P-256", true, func() error { return testECDSA("P-256", crypto.SHA256) }},
+	{"ECDSA P-384", true, func() error { return testECDSA("P-384", crypto.SHA384) }},
+	{"RSA-PSS 2048", true, testRSAPSS},
+	{"MD5", false, testMD5},
+	{"RC4", false, testRC4},
+	{"ChaCha20-Poly1305", false, testChaCha20},
+}
+
+// Run runs the self-tests in FIPS mode. OpenSSL must already be initialized
+// through opensslsetup. If FIPS mode is not enabled, Run enables it for the
+// duration of the tests and disables it again afterwards; if it cannot be
+// enabled, the tests run in the current mode and the report records why.
+//
+// Switching FIPS mode changes process-wide OpenSSL state, so Run must not be
+// called concurrently with other OpenSSL use.
+func Run(ctx context.Context) (report Report, err error) {
+	lib, err := opensslsetup.Loaded()
+	if err != nil {
+		return Report{}, err
+	}
+	report.Library = lib
+
+	report.FIPSMode = openssl.FIPS()
+	if !report.FIPSMode {
+		if err := openssl.SetFIPS(true); err != nil {
+			report.FIPSModeErr = err
+		} else {
+			report.FIPSMode = true
+			defer func() {
+				report.FIPSRestoreErr = openssl.SetFIPS(false)
+			}()
+		}
+	}
+
+	for _, t := range tests {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		report.Results = append(report.Results, runTest(t))
+	}
+	return report, nil
+}
+
+// runTest runs t and times it. Panics from the backend count as failures.
+func runTest(t test) (result Result) {
+	result = Result{Name: t.name, Approved: t.approved}
+	start := time.Now()
+	defer func() {
+		result.Duration = time.Since(start)
+		if r := recover(); r != nil {
+			result.Err = fmt.Errorf("panic: %v", r)
+		}
+		result.Passed = result.Err == nil
+	}()
+	result.Err = t.run()
+	return result
+}
+
+// expect compares got with the hex encoded want.
+func expect(got []byte, want string) error {
+	if hex.EncodeToString(got) != want {
+		return fmt.Errorf("got %x, want %s", got, want)
+	}
+	return nil
+}
+
+func testSHA256() error {
+	sum := openssl.SHA256([]byte("abc"))
+	return expect(sum[:], "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad")
+}
+
+func testSHA384() error {
+	sum := openssl.SHA384([]byte("abc"))
+	return expect(sum[:], "cb00753f45a35e8bb5a03d699ac65007272c32ab0eded1631a8b605a43ff5bed8086072ba1e7cc2358baeca134c825a7")
+}
+
+func testSHA512() error {
+	sum := openssl.SHA512([]byte("abc"))
+	return expect(sum[:], "ddaf35a193617abacc417349ae20413112e6fa4e89a97ea20a9eeee64b55d39a2192992a274fc1a836ba3c23a3feebbd454d4423643ce80e2a9ac94fa54ca49f")
+}
+
+// testHMAC uses RFC 4231 test case 2.
+func testHMAC() error {
+	h := openssl.NewHMAC(openssl.NewSHA256, []byte("Jefe"))
+	if h == nil {
+		return errors.New("HMAC-SHA-256 is not available")
+	}
+	h.Write([]byte("what do ya want for nothing?"))
+	return expect(h.Sum(nil), "5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843")
+}
+
+// testAESGCM uses test case 2 of the GCM specification and decrypts the result.
+func testAESGCM() error {
+	block, err := openssl.NewAESCipher(make([]byte, 16))
+	if err != nil {
+		return err
+	}
+	gcmAble, ok := block.(interface {
+		NewGCM(nonceSize, tagSize int) (cipher.AEAD, error)
+	})
+	if !ok {
+		return errors.New("AES-GCM is not available")
+	}
+	aead, err := gcmAble.NewGCM(12, 16)
+	if err != nil {
+		return err
+	}
+	nonce, plaintext := make([]byte, 12), make([]byte, 16)
+	sealed := aead.Seal(nil, nonce, plaintext, nil)
+	if err := expect(sealed, "0388dace60b6a392f328c2b971b2fe78"+"ab6e47d42cec13bdf53a67b21257bddf"); err != nil {
+		return err
+	}
+	opened, err := aead.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(opened, plaintext) {
+		return errors.New("decryption does not match the plaintext")
+	}
+	return nil
+}
+
+// testHKDF uses RFC 5869 test case 1.
+func testHKDF() error {
+	ikm := bytes.Repeat([]byte{0x0b}, 22)
+	salt, _ := hex.DecodeString("000102030405060708090a0b0c")
+	info, _ := hex.DecodeString("f0f1f2f3f4f5f6f7f8f9")
+
+	prk, err := openssl.ExtractHKDF(openssl.NewSHA256, ikm, salt)
+	if err != nil {
+		return err
+	}
+	if err := expect(prk, "077709362c2e32df0ddc3f0dc47bba6390b6c73bb50f9c3122ec844ad7c2b3e5"); err != nil {
+		return err
+	}
+	okm, err := openssl.ExpandHKDFOneShot(openssl.NewSHA256, prk, info, 42)
+	if err != nil {
+		return err
+	}
+	return expect(okm, "3cb25f25faacd57a90434f64d0362f2a2d2d0a90cf1a5a4c5db02d56ecc4c5bf34007208d5b887185865")
+}
+
+// ecdsaVector is an ECDSA signature verification vector: a public key, a
+// message and a signature, which valid tells whether to accept.
+type ecdsaVector struct {
+	qx, qy, msg, r, s string
+	valid             bool
+}
+
+// ecdsaVectors are taken from the CAVP SigVer vectors of FIPS 186-3 ECDSA
+// (CAVS 11.0, SigVer.rsp), by curve: the first accepted and the first
+// rejected vector of [P-256,SHA-256] and [P-384,SHA-384].
+var ecdsaVectors = map[string][]ecdsaVector{
+	"P-256": {
+		{
+			msg:   "e1130af6a38ccb412a9c8d13e15dbfc9e69a16385af3c3f1e5da954fd5e7c45fd75e2b8c36699228e92840c0562fbf3772f07e17f1add56588dd45f7450e1217ad239922dd9c32695dc71ff2424ca0dec1321aa47064a044b7fe3c2b97d03ce470a592304c5ef21eed9f93da56bb232d1eeb0035f9bf0dfafdcc4606272b20a3",
+			qx:    "e424dc61d4bb3cb7ef4344a7f8957a0c5134e16f7a67c074f82e6e12f49abf3c",
+			qy:    "970eed7aa2bc48651545949de1dddaf0127e5965ac85d1243d6f60e7dfaee927",
+			r:     "bf96b99aa49c705c910be33142017c642ff540c76349b9dab72f981fd9347f4f",
+			s:     "17c55095819089c2e03b9cd415abdf12444e323075d98f31920b9e0f57ec871c",
+			valid: true,
+		},
+		{
+			// S changed
+			msg: "e4796db5f785f207aa30d311693b3702821dff1168fd2e04c0836825aefd850d9aa60326d88cde1a23c7745351392ca2288d632c264f197d05cd424a30336c19fd09bb229654f0222fcb881a4b35c290a093ac159ce13409111ff0358411133c24f5b8e2090d6db6558afc36f06ca1f6ef779785adba68db27a409859fc4c4a0",
+			qx:  "87f8f2b218f49845f6f10eec3877136269f5c1a54736dbdf69f89940cad41555",
+			qy:  "e15f369036f49842fac7a86c8a2b0557609776814448b8f5e84aa9f4395205e9",
+			r:   "d19ff48b324915576416097d2544f7cbdf8768b1454ad20e0baac50e211f23b0",
+			s:   "a3e81e59311cdfff2d4784949f7a2cb50ba6c3a91fa54710568e61aca3e847c6",
+		},
+	},
+	"P-384": {
+		{
+			msg:   "9dd789ea25c04745d57a381f22de01fb0abd3c72dbdefd44e43213c189583eef85ba662044da3de2dd8670e6325154480155bbeebb702c75781ac32e13941860cb576fe37a05b757da5b5b418f6dd7c30b042e40f4395a342ae4dce05634c33625e2bc524345481f7e253d9551266823771b251705b4a85166022a37ac28f1bd",
+			qx:    "cb908b1fd516a57b8ee1e14383579b33cb154fece20c5035e2b3765195d1951d75bd78fb23e00fef37d7d064fd9af144",
+			qy:    "cd99c46b5857401ddcff2cf7cf822121faf1cbad9a011bed8c551f6f59b2c360f79bfbe32adbcaa09583bdfdf7c374bb",
+			r:     "33f64fb65cd6a8918523f23aea0bbcf56bba1daca7aff817c8791dc92428d605ac629de2e847d43cee55ba9e4a0e83ba",
+			s:     "4428bb478a43ac73ecd6de51ddf7c28ff3c2441625a081714337dd44fea8011bae71959a10947b6ea33f77e128d3c6ae",
+			valid: true,
+		},
+		{
+			// R changed
+			msg: "4132833a525aecc8a1a6dea9f4075f44feefce810c4668423b38580417f7bdca5b21061a45eaa3cbe2a7035ed189523af8002d65c2899e65735e4d93a16503c145059f365c32b3acc6270e29a09131299181c98b3c76769a18faf21f6b4a8f271e6bf908e238afe8002e27c63417bda758f846e1e3b8e62d7f05ebd98f1f9154",
+			qx:  "1f94eb6f439a3806f8054dd79124847d138d14d4f52bac93b042f2ee3cdb7dc9e09925c2a5fee70d4ce08c61e3b19160",
+			qy:  "1c4fd111f6e33303069421deb31e873126be35eeb436fe2034856a3ed1e897f26c846ee3233cd16240989a7990c19d8c",
+			r:   "3c15c3cedf2a6fbff2f906e661f5932f2542f0ce68e2a8182e5ed3858f33bd3c5666f17ac39e52cb004b80a0d4ba73cd",
+			s:   "9de879083cbb0a97973c94f1963d84f581e4c6541b7d000f9850deb25154b23a37dd72267bdd72665cc7027f88164fab",
+		},
+	},
+}
+
+// testECDSA verifies the known-answer vectors of curve, then runs a pairwise
+// consistency test of signing, whose signatures are randomized.
+func testECDSA(curve string, h crypto.Hash) error {
+	for i, v := range ecdsaVectors[curve] {
+		pub, err := openssl.NewPublicKeyECDSA(curve, bbig.Enc(fromHex(v.qx)), bbig.Enc(fromHex(v.qy)))
+		if err != nil {
+			return err
+		}
+		msg, _ := hex.DecodeString(v.msg)
+		sig, err := asn1.Marshal(struct{ R, S *big.Int }{fromHex(v.r), fromHex(v.s)})
+		if err != nil {
+			return err
+		}
+		if openssl.VerifyECDSA(pub, hashSum(h, msg), sig) != v.valid {
+			return fmt.Errorf("known-answer vector %d: expected verification to return %t", i, v.valid)
+		}
+	}
+
+	x, y, d, err := openssl.GenerateKeyECDSA(curve)
+	if err != nil {
+		return err
+	}
+	priv, err := openssl.NewPrivateKeyECDSA(curve, x, y, d)
+	if err != nil {
+		return err
+	}
+	pub, err := openssl.NewPublicKeyECDSA(curve, x, y)
+	if err != nil {
+		return err
+	}
+	digest := digest(h)
+	sig, err := openssl.SignMarshalECDSA(priv, digest)
+	if err != nil {
+		return err
+	}
+	if !openssl.VerifyECDSA(pub, digest, sig) {
+		return errors.New("signature does not verify")
+	}
+	digest[0] ^= 0xff
+	if openssl.VerifyECDSA(pub, digest, sig) {
+		return errors.New("signature verifies for a different digest")
+	}
+	return nil
+}
+
+// rsaPSSVector is an RSA-PSS SHA-256 signature, with a 32-byte salt, of msg
+// under the 2048-bit public key n, e. No CAVP SigVerPSS file is at hand, so
+// it was produced once by Go's crypto/rsa, with a key generated for it, and
+// checked with its VerifyPSS.
+var rsaPSSVector = struct {
+	n   string
+	e   int64
+	msg string
+	sig string
+}{
+	n:   "cc40ac4eb28f3fc902f529e281af31309db10e39b6099620b89baad37ae2f820e15d310f5cd8ec7eeb86afad93d4cfc003893f56f0774f1fa82c147f445f113139859bb602cb4b0774986a66c53f5f0acfc9ff1c5f4e59b422fea019260df42dbefd2cc9660e51bd8474c2d2deaae53a7d7fdf3acf148d94e5392ed4eda41706ccb65040e98c6852c4d55cbe95c0ee90e4de9047f03f20b80882d1889911b66c8e64f067eaebdaecacdb58a02b78cc58d32aea7034637c93d362d065afbc50f31855b65b962d27146b2891e4bf563d252acca911df88d7a48a7facb2ed21288f1e0635e9d621f0b8610cd4fe780b0814cb5ce2c40632c4c6640ae3d0ce346a39",
+	e:   65537,
+	msg: "fips-check RSA-PSS known-answer test",
+	sig: "b230dfd32542e51f41c573fc99b507662a3c518d062307af33dbc6aa475cd98f80ff31039c7a8cd6020d2ad38da65c25d8b4fa7ea4812e8248139d416d63f6ed105963827c3cdaab9307407004d59c150167f0b624d99cad0270b03240ee8a34d23138ae96d4c0f74c53703495235216ea17be08f5857cfc66b7f35e0e99d21a0eeb0398486e13dc5366f4ce255b35829953ee3629e96b6d4af55fe8eb9f2be8b2f272c2f21a7cd665a00768f4602a14cee66c26b0970cf5375bbb150116aa803c5b0b9351f0ab83b792015cefb06aa2c88bec912cb6264a7e2584e67343bcb2ab3db151f1095e894ed015232457633740dd99f3346745980872a74b4aceddda",
+}
+
+// testRSAPSS verifies the known-answer vector, then runs a pairwise
+// consistency test of signing, whose signatures are randomized.
+func testRSAPSS() error {
+	v := rsaPSSVector
+	knownPub, err := openssl.NewPublicKeyRSA(bbig.Enc(fromHex(v.n)), bbig.Enc(big.NewInt(v.e)))
+	if err != nil {
+		return err
+	}
+	knownSig, _ := hex.DecodeString(v.sig)
+	hashed := hashSum(crypto.SHA256, []byte(v.msg))
+	if err := openssl.VerifyRSAPSS(knownPub, crypto.SHA256, hashed, knownSig, 32); err != nil {
+		return fmt.Errorf("known-answer signature does not verify: %w", err)
+	}
+	knownSig[len(knownSig)-1] ^= 0xff
+	if openssl.VerifyRSAPSS(knownPub, crypto.SHA256, hashed, knownSig, 32) == nil {
+		return errors.New("altered known-answer signature verifies")
+	}
+
+	n, e, d, p, q, dp, dq, qinv, err := openssl.GenerateKeyRSA(2048)
+	if err != nil {
+		return err
+	}
+	priv, err := openssl.NewPrivateKeyRSA(n, e, d, p, q, dp, dq, qinv)
+	if err != nil {
+		return err
+	}
+	pub, err := openssl.NewPublicKeyRSA(n, e)
+	if err != nil {
+		return err
+	}
+	digest := digest(crypto.SHA256)
+	sig, err := openssl.SignRSAPSS(priv, crypto.SHA256, digest, 32)
+	if err != nil {
+		return err
+	}
+	if err := openssl.VerifyRSAPSS(pub, crypto.SHA256, digest, sig, 32); err != nil {
+		return fmt.Errorf("signature does not verify: %w", err)
+	}
+	digest[0] ^= 0xff
+	if openssl.VerifyRSAPSS(pub, crypto.SHA256, digest, sig, 32) == nil {
+		return errors.New("signature verifies for a different digest")
+	}
+	return nil
+}
+
+// fromHex decodes a hex encoded integer of a test vector.
+func fromHex(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid test vector integer " + s)
+	}
+	return n
+}
+
+// digest returns the digest of a fixed message.
+func digest(h crypto.Hash) []byte {
+	return hashSum(h, []byte("fips-check self-test message"))
+}
+
+// hashSum returns the digest of msg with h, which is SHA-256 or SHA-384.
+func hashSum(h crypto.Hash, msg []byte) []byte {
+	switch h {
+	case crypto.SHA384:
+		sum := openssl.SHA384(msg)
+		return sum[:]
+	default:
+		sum := openssl.SHA256(msg)
+		return sum[:]
+	}
+}
+
+// errNotRejected is returned when a non-approved algorithm is usable.
+var errNotRejected = errors.New("non-approved algorithm was not rejected")
+
+func testMD5() (err error) {
+	defer func() {
+		// The backend panics when the digest cannot be computed
+		if recover() != nil {
+			err = nil
+		}
+	}()
+	openssl.MD5([]byte("abc"))
+	return errNotRejected
+}
+
+func testRC4() (err error) {
+	defer func() {
+		// The backend panics when the cipher is unsupported
+		if recover() != nil {
+			err = nil
+		}
+	}()
+	if _, err := openssl.NewRC4Cipher(make([]byte, 16)); err != nil {
+		return nil
+	}
+	return errNotRejected
+}
+
+func testChaCha20() error {
+	lib, err := opensslsetup.Loaded()
+	if err != nil {
+		return err
+	}
+	accepted, err := cipherAccepted(lib, "chacha20-poly1305")
+	if err != nil {
+		return err
+	}
+	if accepted {
+		return errNotRejected
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+// Package testutil provides fixtures shared by the tests of several packages.
+package testutil
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestBinary returns the content of the running test binary, which is a Go
+// binary. It skips the test if the test binary cannot be located.
+func TestBinary(t testing.TB) []byte {
+	t.Helper()
+	self, err := os.Executable()
+	if err != nil {
+		t.Skipf("Cannot locate test binary: %v", err)
+	}
+	data, err := os.ReadFile(self)
+	if err != nil {
+		t.Fatalf("Failed to read test binary: %v", err)
+	}
+	return data
+}
+
+// WriteTestBinaries copies the running test binary, as returned by
+// TestBinary, to each of the slash-separated names below root, creating
+// their directories, and returns its content. The copies must never be
+// executed, or they would run the test suite again.
+func WriteTestBinaries(t testing.TB, root string, names ...string) []byte {
+	t.Helper()
+	data := TestBinary(t)
+	for _, name := range names {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, data, 0755); err != nil {
+			t.Fatalf("Failed to write test binary: %v", err)
+		}
+	}
+	return data
+}
+
+// WriteFiles writes files, by slash-separated name relative to root, with
+// their content, creating their directories.
+func WriteFiles(t testing.TB, root string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+// Package verdict combines the per-binary reports of a root filesystem with
+// the crypto runtime found in that same filesystem into one overall verdict.
+// Unlike the per-binary status, it does not depend on the OpenSSL of the host
+// the checker runs on.
+package verdict
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+// Options configures the evaluation.
+type Options struct {
+	// UseRuntimeProbe includes the outcome of the runtime probes. It should
+	// only be set when the checker runs inside the target filesystem, as the
+	// probes otherwise load the host's OpenSSL rather than the target's.
+	UseRuntimeProbe bool
+	// Entrypoint is the path of the executable a container image runs by
+	// default, if known. The report flagged as Entrypoint is judged apart
+	// from the incidental binaries.
+	Entrypoint string
+}
+
+// Binary is the verdict for a single Go binary.
+type Binary struct {
+	Path      string
+	Compliant bool
+	// Reason explains a non-compliant verdict
+	Reason string
+}
+
+// Report is the overall verdict for a root filesystem.
+type Report struct {
+	Compliant bool
+	// Reasons explain the verdict, failures first
+	Reasons []string
+	// RuntimeFIPSCapable reports whether the filesystem ships libcrypto and a
+	// FIPS provider that its configuration activates
+	RuntimeFIPSCapable bool
+	// Entrypoint is the verdict for the binary the image runs by default, if it is a Go binary
+	Entrypoint *Binary
+	Binaries   []Binary
+}
+
+// Evaluate combines the crypto runtime inventory, the FIPS provider modules
+// found by cmvp.FindModules and the binary reports of one root filesystem.
+func Evaluate(inv cryptoinventory.Inventory, modules []cmvp.Detected, reports []report.BinaryReport, opts Options) Report {
+	var r Report
+	var failures, notes []string
+
+	switch {
+	case !inv.HasLibcrypto():
+		failures = append(failures, "no libcrypto found: Go systemcrypto binaries cannot load OpenSSL")
+	case len(modules) == 0:
+		failures = append(failures, "libcrypto found, but no FIPS provider (ossl-modules/fips.so or symcryptprovider.so)")
+	default:
+		var inactive []string
+		for _, m := range modules {
+			if missing := missingActivation(inv, m); missing != "" {
+				inactive = append(inactive, missing)
+				continue
+			}
+			r.RuntimeFIPSCapable = true
+			notes = append(notes, fmt.Sprintf("FIPS provider %s %s at %s", m.Module, m.Version, m.Path))
+		}
+		switch {
+		case !r.RuntimeFIPSCapable:
+			failures = append(failures, inactive...)
+		case !defaultPropertiesFIPS(inv):
+			notes = append(notes, "no openssl.cnf sets default_properties fips=yes: only applications that request FIPS mode, such as Go with GOFIPS=1, use the FIPS provider")
+		}
+	}
+
+	if !inv.Diagnostics.Complete() {
+		notes = append(notes, fmt.Sprintf("%d paths could not be read, so the crypto runtime inventory may be incomplete", inv.Diagnostics.Count))
+	}
+
+	var failed []string
+	incidental := 0
+	for _, report := range reports {
+		b := evaluateBinary(report, opts)
+		r.Binaries = append(r.Binaries, b)
+		if report.Entrypoint {
+			r.Entrypoint = &b
+			if b.Compliant {
+				notes = append(notes, fmt.Sprintf("entrypoint %s uses systemcrypto", b.Path))
+			} else {
+				failures = append(failures, fmt.Sprintf("entrypoint %s is not compliant: %s", b.Path, b.Reason))
+			}
+			continue
+		}
+		incidental++
+		if !b.Compliant {
+			failed = append(failed, fmt.Sprintf("%s (%s)", b.Path, b.Reason))
+		}
+	}
+	if r.Entrypoint == nil && opts.Entrypoint != "" {
+		notes = append(notes, fmt.Sprintf("entrypoint %s is not a Go binary", opts.Entrypoint))
+	}
+	binaries := "Go binaries"
+	if r.Entrypoint != nil {
+		binaries = "other Go binaries"
+	}
+	switch {
+	case len(failed) > 0:
+		failures = append(failures, fmt.Sprintf("%d of %d %s are not compliant: %s",
+			len(failed), incidental, binaries, strings.Join(failed, ", ")))
+	case len(reports) == 0:
+		notes = append(notes, "no Go binaries found")
+	case incidental > 0:
+		notes = append(notes, fmt.Sprintf("all %d %s use systemcrypto", incidental, binaries))
+	}
+	if !opts.UseRuntimeProbe && len(reports) > 0 {
+		notes = append(notes, "binaries were checked statically; runtime probes do not reflect this filesystem's OpenSSL")
+	}
+
+	r.Compliant = len(failures) == 0
+	r.Reasons = append(failures, notes...)
+	return r
+}
+
+// missingActivation explains why the configuration of the filesystem does not
+// activate the FIPS provider module m, or returns an empty string if it does.
+// The OpenSSL FIPS provider needs a fipsmodule.cnf that activates it, as
+// written by openssl fipsinstall; other providers need an openssl.cnf whose
+// providers section activates them.
+func missingActivation(inv cryptoinventory.Inventory, m cmvp.Detected) string {
+	if m.Module == cmvp.OpenSSLFIPSProvider {
+		var inactive []string
+		for _, item := range inv.Items {
+			if item.Kind != cryptoinventory.KindFIPSModuleConfig || item.LinkTarget != "" {
+				continue
+			}
+			if item.Activated {
+				return ""
+			}
+			inactive = append(inactive, item.Path)
+		}
+		if len(inactive) == 0 {
+			return fmt.Sprintf("FIPS provider found at %s, but no fipsmodule.cnf configures it", m.Path)
+		}
+		return fmt.Sprintf("FIPS provider found at %s, but %s does not activate it", m.Path, strings.Join(inactive, ", "))
+	}
+
+	provider := strings.TrimSuffix(path.Base(m.Path), ".so")
+	for _, item := range inv.Items {
+		if item.Kind == cryptoinventory.KindOpenSSLConfig && slices.Contains(item.Providers, provider) {
+			return ""
+		}
+	}
+	return fmt.Sprintf("FIPS provider found at %s, but no openssl.cnf activates the %s provider", m.Path, provider)
+}
+
+// defaultPropertiesFIPS reports whether an openssl.cnf of the filesystem
+// makes FIPS the default for every application, with default_properties fips=yes.
+func defaultPropertiesFIPS(inv cryptoinventory.Inventory) bool {
+	for _, item := range inv.Items {
+		if item.Kind == cryptoinventory.KindOpenSSLConfig &&
+			strings.Contains(strings.ReplaceAll(item.DefaultProperties, " ", ""), "fips=yes") {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluateBinary(report report.BinaryReport, opts Options) Binary {
+	details := report.GoBinaryDetails
+	b := Binary{Path: report.RelativePath}
+	switch {
+	case !details.UseSystemcrypto:
+		b.Reason = "systemcrypto not in use"
+	case !details.CGOEnabled:
+		b.Reason = "built without cgo, so OpenSSL cannot be loaded"
+	case opts.UseRuntimeProbe && details.FailsOnFIPSCheck:
+		b.Reason = fmt.Sprintf("runtime check fails: %s", details.RuntimeOutcome)
+	default:
+		b.Compliant = true
+	}
+	return b
+}
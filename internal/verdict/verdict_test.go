@@ -0,0 +1,179 @@
+package verdict
+
+import (
+	"io/fs"
+	"strings"
+	"testing"
+
+	"github.com/bahe-msft/fips-check/internal/cmvp"
+	"github.com/bahe-msft/fips-check/internal/cryptoinventory"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+func binary(path string, details report.GoBinaryReportDetails) report.BinaryReport {
+	return report.BinaryReport{RelativePath: path, GoBinaryDetails: details}
+}
+
+func TestEvaluate(t *testing.T) {
+	fipsRuntime := cryptoinventory.Inventory{Items: []cryptoinventory.Item{
+		{Kind: cryptoinventory.KindLibcrypto, Path: "/usr/lib64/libcrypto.so.3"},
+		{Kind: cryptoinventory.KindFIPSProvider, Path: "/usr/lib64/ossl-modules/fips.so"},
+		{Kind: cryptoinventory.KindFIPSModuleConfig, Path: "/etc/pki/tls/fipsmodule.cnf", Activated: true},
+		{Kind: cryptoinventory.KindOpenSSLConfig, Path: "/etc/pki/tls/openssl.cnf", Providers: []string{"fips"}, DefaultProperties: "fips=yes"},
+	}}
+	fipsModules := []cmvp.Detected{{Module: cmvp.OpenSSLFIPSProvider, Version: "3.0.8", Path: "/usr/lib64/ossl-modules/fips.so"}}
+	inactiveProvider := cryptoinventory.Inventory{Items: []cryptoinventory.Item{
+		{Kind: cryptoinventory.KindLibcrypto, Path: "/usr/lib64/libcrypto.so.3"},
+		{Kind: cryptoinventory.KindFIPSProvider, Path: "/usr/lib64/ossl-modules/fips.so"},
+		{Kind: cryptoinventory.KindFIPSModuleConfig, Path: "/etc/pki/tls/fipsmodule.cnf"},
+	}}
+	symcryptRuntime := cryptoinventory.Inventory{Items: []cryptoinventory.Item{
+		{Kind: cryptoinventory.KindLibcrypto, Path: "/usr/lib/libcrypto.so.3"},
+		{Kind: cryptoinventory.KindOpenSSLConfig, Path: "/etc/pki/tls/openssl.cnf", Providers: []string{"default", "symcryptprovider"}},
+	}}
+	symcryptModules := []cmvp.Detected{{Module: cmvp.SymCryptOpenSSL, Version: "1.5.1", Path: "/usr/lib/ossl-modules/symcryptprovider.so"}}
+	noFIPSProvider := cryptoinventory.Inventory{Items: []cryptoinventory.Item{
+		{Kind: cryptoinventory.KindLibcrypto, Path: "/usr/lib/libcrypto.so.3"},
+	}}
+
+	good := report.GoBinaryReportDetails{UseSystemcrypto: true, CGOEnabled: true}
+	failsProbe := good
+	failsProbe.FailsOnFIPSCheck = true
+	failsProbe.RuntimeOutcome = report.RuntimeOutcomeFIPSPanic
+
+	tests := []struct {
+		name      string
+		inv       cryptoinventory.Inventory
+		modules   []cmvp.Detected
+		reports   []report.BinaryReport
+		opts      Options
+		compliant bool
+		reason    string
+	}{
+		{
+			name:      "compliant",
+			inv:       fipsRuntime,
+			modules:   fipsModules,
+			reports:   []report.BinaryReport{binary("app", good)},
+			compliant: true,
+			reason:    "all 1 Go binaries use systemcrypto",
+		},
+		{
+			name: "incomplete_inventory",
+			inv: cryptoinventory.Inventory{Items: fipsRuntime.Items, Diagnostics: report.ScanDiagnostics{
+				Count:  1,
+				Errors: []report.PathError{{Path: "/opt", Err: fs.ErrPermission}},
+			}},
+			modules:   fipsModules,
+			reports:   []report.BinaryReport{binary("app", good)},
+			compliant: true,
+			reason:    "1 paths could not be read, so the crypto runtime inventory may be incomplete",
+		},
+		{
+			name:    "no_libcrypto",
+			reports: []report.BinaryReport{binary("app", good)},
+			reason:  "no libcrypto found",
+		},
+		{
+			name:    "no_fips_provider",
+			inv:     noFIPSProvider,
+			reports: []report.BinaryReport{binary("app", good)},
+			reason:  "no FIPS provider",
+		},
+		{
+			name:    "fips_provider_not_configured",
+			inv:     cryptoinventory.Inventory{Items: fipsRuntime.Items[:2]},
+			modules: fipsModules,
+			reports: []report.BinaryReport{binary("app", good)},
+			reason:  "FIPS provider found at /usr/lib64/ossl-modules/fips.so, but no fipsmodule.cnf configures it",
+		},
+		{
+			name:    "fips_provider_not_activated",
+			inv:     inactiveProvider,
+			modules: fipsModules,
+			reports: []report.BinaryReport{binary("app", good)},
+			reason:  "but /etc/pki/tls/fipsmodule.cnf does not activate it",
+		},
+		{
+			name:      "symcrypt_provider_activated",
+			inv:       symcryptRuntime,
+			modules:   symcryptModules,
+			reports:   []report.BinaryReport{binary("app", good)},
+			compliant: true,
+			reason:    "no openssl.cnf sets default_properties fips=yes",
+		},
+		{
+			name:    "symcrypt_provider_not_activated",
+			inv:     cryptoinventory.Inventory{Items: symcryptRuntime.Items[:1]},
+			modules: symcryptModules,
+			reports: []report.BinaryReport{binary("app", good)},
+			reason:  "no openssl.cnf activates the symcryptprovider provider",
+		},
+		{
+			name:    "not_systemcrypto",
+			inv:     fipsRuntime,
+			modules: fipsModules,
+			reports: []report.BinaryReport{binary("app", good), binary("other", report.GoBinaryReportDetails{})},
+			reason:  "1 of 2 Go binaries are not compliant: other (systemcrypto not in use)",
+		},
+		{
+			name:      "runtime_probe_ignored_outside_target",
+			inv:       fipsRuntime,
+			modules:   fipsModules,
+			reports:   []report.BinaryReport{binary("app", failsProbe)},
+			compliant: true,
+			reason:    "checked statically",
+		},
+		{
+			name:    "runtime_probe_inside_target",
+			inv:     fipsRuntime,
+			modules: fipsModules,
+			reports: []report.BinaryReport{binary("app", failsProbe)},
+			opts:    Options{UseRuntimeProbe: true},
+			reason:  "runtime check fails: fips-panic",
+		},
+		{
+			name:    "entrypoint_not_compliant",
+			inv:     fipsRuntime,
+			modules: fipsModules,
+			reports: []report.BinaryReport{
+				{RelativePath: "app", Entrypoint: true},
+				binary("helper", good),
+			},
+			opts:   Options{Entrypoint: "app"},
+			reason: "entrypoint app is not compliant: systemcrypto not in use",
+		},
+		{
+			name:    "entrypoint_apart_from_incidental",
+			inv:     fipsRuntime,
+			modules: fipsModules,
+			reports: []report.BinaryReport{
+				{RelativePath: "app", Entrypoint: true, GoBinaryDetails: good},
+				binary("helper", report.GoBinaryReportDetails{}),
+			},
+			opts:   Options{Entrypoint: "app"},
+			reason: "1 of 1 other Go binaries are not compliant: helper (systemcrypto not in use)",
+		},
+		{
+			name:      "entrypoint_not_go",
+			inv:       fipsRuntime,
+			modules:   fipsModules,
+			reports:   []report.BinaryReport{binary("helper", good)},
+			opts:      Options{Entrypoint: "bin/sh"},
+			compliant: true,
+			reason:    "entrypoint bin/sh is not a Go binary",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Evaluate(tt.inv, tt.modules, tt.reports, tt.opts)
+			if r.Compliant != tt.compliant {
+				t.Errorf("Expected compliant=%t, got %t: %v", tt.compliant, r.Compliant, r.Reasons)
+			}
+			if !strings.Contains(strings.Join(r.Reasons, "\n"), tt.reason) {
+				t.Errorf("Expected a reason containing %q, got %v", tt.reason, r.Reasons)
+			}
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package fipscheck
+
+import (
+	"log/slog"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/binarychecker"
+	"github.com/bahe-msft/fips-check/internal/cache"
+)
+
+// Option configures a scan by CheckBinaries, CheckBinariesStatic,
+// CheckBinariesFS, ScanBinaries and ScanBinariesFS.
+type Option func(*scanConfig)
+
+// scanConfig collects the options of a single scan.
+type scanConfig struct {
+	options binarychecker.Options
+}
+
+// newScanConfig applies opts, followed by the options the caller enforces.
+func newScanConfig(opts []Option, enforced ...Option) scanConfig {
+	var c scanConfig
+	for _, opt := range opts {
+		opt(&c)
+	}
+	for _, opt := range enforced {
+		opt(&c)
+	}
+	if c.options.Cache != nil && !c.options.DisableRuntimeProbe {
+		c.options.HostFingerprint = hostFingerprint()
+	}
+	return c
+}
+
+// SymlinkPolicy controls how symlinks found during a scan are handled.
+type SymlinkPolicy = binarychecker.SymlinkPolicy
+
+// Symlink policies for WithSymlinkPolicy.
+const (
+	// SymlinkFollow reports symlinks to Go binaries as aliases of their
+	// targets, resolved inside the scan root. This is the default.
+	SymlinkFollow = binarychecker.SymlinkFollow
+	// SymlinkSkip ignores symlinks; only their targets are checked, if found.
+	SymlinkSkip = binarychecker.SymlinkSkip
+)
+
+// Analyzer identifies a kind of binary analysis, as recorded in BinaryReport.Type.
+type Analyzer = binarychecker.Analyzer
+
+// AnalyzerGoBinary analyzes Go binaries.
+const AnalyzerGoBinary = binarychecker.AnalyzerGoBinary
+
+// WithConcurrency sets the number of binaries executed at once for the
+// runtime FIPS check. The default is 10.
+func WithConcurrency(n int) Option {
+	return func(c *scanConfig) { c.options.Concurrency = n }
+}
+
+// WithAnalysisConcurrency sets the number of files parsed and hashed at once.
+// The default is runtime.GOMAXPROCS.
+func WithAnalysisConcurrency(n int) Option {
+	return func(c *scanConfig) { c.options.AnalysisConcurrency = n }
+}
+
+// WithRuntimeTimeout sets how long a binary may run during the runtime FIPS
+// check before it is stopped. The default is 2 seconds.
+func WithRuntimeTimeout(d time.Duration) Option {
+	return func(c *scanConfig) { c.options.RuntimeTimeout = d }
+}
+
+// WithRuntimeProbe enables or disables the runtime FIPS check, which executes
+// each binary with GOFIPS=1. It is enabled by default; without it every
+// report is based on static analysis only.
+func WithRuntimeProbe(enabled bool) Option {
+	return func(c *scanConfig) { c.options.DisableRuntimeProbe = !enabled }
+}
+
+// WithInclude restricts the scan to files matching one of patterns.
+// Patterns use path.Match syntax. Patterns containing a slash match the
+// slash-separated path relative to the scan root, e.g. "usr/bin/*";
+// others match the base name, e.g. "kube*".
+func WithInclude(patterns ...string) Option {
+	return func(c *scanConfig) { c.options.Include = append(c.options.Include, patterns...) }
+}
+
+// WithExclude skips files and whole directories matching one of patterns,
+// which are matched as in WithInclude. Exclusion takes precedence over inclusion.
+func WithExclude(patterns ...string) Option {
+	return func(c *scanConfig) { c.options.Exclude = append(c.options.Exclude, patterns...) }
+}
+
+// WithSymlinkPolicy sets how symlinks are handled.
+func WithSymlinkPolicy(p SymlinkPolicy) Option {
+	return func(c *scanConfig) { c.options.Symlinks = p }
+}
+
+// WithMaxFileSize skips files larger than n bytes. There is no limit by default.
+func WithMaxFileSize(n int64) Option {
+	return func(c *scanConfig) { c.options.MaxFileSize = n }
+}
+
+// WithAnalyzers selects the analyzers to run. All analyzers run by default.
+// Selecting an unknown analyzer makes the scan fail.
+func WithAnalyzers(analyzers ...Analyzer) Option {
+	return func(c *scanConfig) { c.options.Analyzers = analyzers }
+}
+
+// WithProbeEnv replaces the environment that binaries inherit from the
+// current process during the runtime FIPS check. GOFIPS=1 is always added.
+func WithProbeEnv(env []string) Option {
+	return func(c *scanConfig) { c.options.Env = env }
+}
+
+// WithLogger sets a logger for debug logs about skipped files and checks.
+// Nothing is logged by default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(c *scanConfig) { c.options.Logger = logger }
+}
+
+// WithStrict makes a scan fail with report.ErrIncompleteScan if any path
+// could not be scanned. By default such paths are skipped; Scan lists them
+// in the diagnostics of its result.
+func WithStrict(strict bool) Option {
+	return func(c *scanConfig) { c.options.Strict = strict }
+}
+
+// WithCache stores analysis results in dir and reuses them for binaries with
+// the same SHA-256 digest in later scans. Results are keyed by the checker
+// version and the scan options that affect them; when the runtime FIPS check
+// is enabled, also by the host's OpenSSL, which is loaded to identify it.
+// Cached reports have Cached set. An empty dir disables the cache.
+func WithCache(dir string) Option {
+	return func(c *scanConfig) {
+		c.options.Cache = nil
+		if dir != "" {
+			c.options.Cache = cache.New(dir)
+		}
+	}
+}
+
+// WithProgress sets a callback that is called whenever the scan makes progress.
+// Calls are serialized but may come from different goroutines, so it should
+// return quickly.
+func WithProgress(progress func(ScanProgress)) Option {
+	return func(c *scanConfig) {
+		c.options.Progress = nil
+		if progress != nil {
+			c.options.Progress = func(p binarychecker.Progress) { progress(ScanProgress(p)) }
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ErrIncompleteScan means parts of the tree could not be scanned. Strict
+// scans fail with it; see ScanDiagnostics.Err.
+var ErrIncompleteScan = errors.New("scan coverage is incomplete")
+
+// maxDiagnosticErrors bounds the errors kept by ScanDiagnostics, so that a
+// broken mount cannot exhaust memory. Count keeps counting past it.
+const maxDiagnosticErrors = 100
+
+// ScanDiagnostics describes the parts of a tree a scan could not cover, such
+// as directories that could not be read because of missing permissions.
+// Binaries below them are missing from the scan.
+type ScanDiagnostics struct {
+	// Count is the number of paths that could not be scanned
+	Count int `json:"count"`
+	// Errors lists the first of them
+	Errors []PathError `json:"errors,omitempty"`
+}
+
+// Add records that path could not be scanned.
+func (d *ScanDiagnostics) Add(path string, err error) {
+	d.Count++
+	if len(d.Errors) < maxDiagnosticErrors {
+		d.Errors = append(d.Errors, PathError{Path: path, Err: err})
+	}
+}
+
+// Complete reports whether the whole tree was scanned.
+func (d ScanDiagnostics) Complete() bool {
+	return d.Count == 0
+}
+
+// Err returns nil if the whole tree was scanned, and an error wrapping
+// ErrIncompleteScan otherwise.
+func (d ScanDiagnostics) Err() error {
+	if d.Complete() {
+		return nil
+	}
+	return fmt.Errorf("%w: %d paths could not be scanned, first %v", ErrIncompleteScan, d.Count, d.Errors[0])
+}
+
+// PathError is an error for a path relative to the scan root.
+type PathError struct {
+	Path string `json:"path"`
+	Err  error  `json:"-"`
+}
+
+func (e PathError) Error() string {
+	return e.Path + ": " + e.Err.Error()
+}
+
+func (e PathError) Unwrap() error {
+	return e.Err
+}
+
+// MarshalJSON encodes the error as its message.
+func (e PathError) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}{e.Path, e.Err.Error()})
+}
+
+// UnmarshalJSON decodes an error encoded by MarshalJSON. The decoded Err
+// only preserves the message of the original error.
+func (e *PathError) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Path  string `json:"path"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*e = PathError{Path: v.Path, Err: errors.New(v.Error)}
+	return nil
+}
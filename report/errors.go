@@ -0,0 +1,57 @@
+package report
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Errors recorded in BinaryReport.Error. They are wrapped with details, so
+// test for them with errors.Is. Errors matching them are scanner failures:
+// they say the binary could not be fully checked, not that it is non-compliant.
+var (
+	// ErrNotELF means the file is not an ELF binary.
+	ErrNotELF = errors.New("not an ELF binary")
+	// ErrNoBuildInfo means the binary carries no Go build info, so it is
+	// either not a Go binary or was stripped of it.
+	ErrNoBuildInfo = errors.New("no Go build info")
+	// ErrForeignArch means the binary was built for an architecture this host
+	// cannot execute, as read from its ELF header or because the kernel
+	// refused to execute it, so the runtime check could not run.
+	ErrForeignArch = errors.New("binary built for a foreign architecture")
+	// ErrRuntimeProbeTimeout means the runtime check timed out, either before
+	// the binary could be started or while it was still running. In the
+	// latter case its outcome is RuntimeOutcomeTimeout.
+	ErrRuntimeProbeTimeout = errors.New("runtime FIPS probe timed out")
+	// ErrPermission means the binary could not be read or executed. It is
+	// fs.ErrPermission, so errors from the os package match it.
+	ErrPermission = fs.ErrPermission
+)
+
+// ExecError describes a runtime check that did not produce a conclusive outcome,
+// because the binary could not be started or ended in an unclassified way.
+type ExecError struct {
+	// Path is the executed file
+	Path string
+	// ExitCode is the exit status of the binary, -1 if it did not exit normally
+	ExitCode int
+	// Signal is the signal that terminated the binary, if any
+	Signal string
+	// Err is the underlying cause, if known
+	Err error
+}
+
+func (e *ExecError) Error() string {
+	switch {
+	case e.Err != nil:
+		return fmt.Sprintf("exec %s: %v", e.Path, e.Err)
+	case e.Signal != "":
+		return fmt.Sprintf("exec %s: signal: %s", e.Path, e.Signal)
+	default:
+		return fmt.Sprintf("exec %s: exit status %d", e.Path, e.ExitCode)
+	}
+}
+
+func (e *ExecError) Unwrap() error {
+	return e.Err
+}
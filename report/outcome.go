@@ -0,0 +1,102 @@
+package report
+
+// RuntimeOutcome classifies how a binary behaved when it was executed with GOFIPS=1.
+type RuntimeOutcome int
+
+const (
+	// RuntimeOutcomeUnknown means the runtime check did not run or could not be classified.
+	RuntimeOutcomeUnknown RuntimeOutcome = iota
+	// RuntimeOutcomeFIPSPanic means the binary panicked because FIPS mode is not available.
+	RuntimeOutcomeFIPSPanic
+	// RuntimeOutcomeOpenSSLLoadFailure means the crypto backend could not initialize OpenSSL.
+	RuntimeOutcomeOpenSSLLoadFailure
+	// RuntimeOutcomeMissingLibcrypto means libcrypto.so could not be found.
+	RuntimeOutcomeMissingLibcrypto
+	// RuntimeOutcomeGlibcMismatch means the dynamic loader rejected the binary
+	// because the required glibc symbol versions are not available.
+	RuntimeOutcomeGlibcMismatch
+	// RuntimeOutcomeWrongArchitecture means the binary cannot be executed on this host.
+	RuntimeOutcomeWrongArchitecture
+	// RuntimeOutcomeNonFIPSPanic means the binary panicked for a reason unrelated to FIPS.
+	RuntimeOutcomeNonFIPSPanic
+	// RuntimeOutcomeCleanExit means the binary exited with status 0.
+	RuntimeOutcomeCleanExit
+	// RuntimeOutcomeNonZeroExit means the binary exited with a non-zero status without panicking.
+	RuntimeOutcomeNonZeroExit
+	// RuntimeOutcomeSignal means the binary was terminated by a signal.
+	RuntimeOutcomeSignal
+	// RuntimeOutcomeTimeout means the binary was still running when the check timed out.
+	RuntimeOutcomeTimeout
+	// RuntimeOutcomeSkipped means the runtime check was deliberately not performed,
+	// for example because the binary was built for a foreign architecture.
+	RuntimeOutcomeSkipped
+	// RuntimeOutcomeMissingLibrary means the dynamic loader could not load a
+	// shared library other than libcrypto, so the binary never started and
+	// the runtime check did not run (since schema 1.6).
+	RuntimeOutcomeMissingLibrary
+)
+
+var runtimeOutcomeNames = [...]string{
+	RuntimeOutcomeUnknown:            "unknown",
+	RuntimeOutcomeFIPSPanic:          "fips-panic",
+	RuntimeOutcomeOpenSSLLoadFailure: "openssl-load-failure",
+	RuntimeOutcomeMissingLibcrypto:   "missing-libcrypto",
+	RuntimeOutcomeGlibcMismatch:      "glibc-mismatch",
+	RuntimeOutcomeWrongArchitecture:  "wrong-architecture",
+	RuntimeOutcomeNonFIPSPanic:       "non-fips-panic",
+	RuntimeOutcomeCleanExit:          "clean-exit",
+	RuntimeOutcomeNonZeroExit:        "non-zero-exit",
+	RuntimeOutcomeSignal:             "signal",
+	RuntimeOutcomeTimeout:            "timeout",
+	RuntimeOutcomeSkipped:            "skipped",
+	RuntimeOutcomeMissingLibrary:     "missing-library",
+}
+
+// String returns the short, stable name of the outcome.
+func (o RuntimeOutcome) String() string {
+	if o < 0 || int(o) >= len(runtimeOutcomeNames) {
+		return "unknown"
+	}
+	return runtimeOutcomeNames[o]
+}
+
+// FailsFIPS reports whether the outcome means the binary cannot run in FIPS mode
+// in the environment where it was executed.
+func (o RuntimeOutcome) FailsFIPS() bool {
+	switch o {
+	case RuntimeOutcomeFIPSPanic,
+		RuntimeOutcomeOpenSSLLoadFailure,
+		RuntimeOutcomeMissingLibcrypto,
+		RuntimeOutcomeGlibcMismatch:
+		return true
+	}
+	return false
+}
+
+// Conclusive reports whether the outcome says anything about FIPS compliance.
+// Binaries that could not be executed at all and skipped checks produce
+// inconclusive outcomes.
+func (o RuntimeOutcome) Conclusive() bool {
+	switch o {
+	case RuntimeOutcomeUnknown, RuntimeOutcomeWrongArchitecture, RuntimeOutcomeSkipped, RuntimeOutcomeMissingLibrary:
+		return false
+	}
+	return true
+}
+
+// MarshalText encodes the outcome as its name.
+func (o RuntimeOutcome) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+// UnmarshalText decodes an outcome name. Names this version does not know,
+// e.g. from a newer producer, decode as RuntimeOutcomeUnknown.
+func (o *RuntimeOutcome) UnmarshalText(text []byte) error {
+	*o = RuntimeOutcomeUnknown
+	for i, name := range runtimeOutcomeNames {
+		if name == string(text) {
+			*o = RuntimeOutcome(i)
+		}
+	}
+	return nil
+}
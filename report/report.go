@@ -0,0 +1,117 @@
+// Package report defines the results of a binary scan. The same types are
+// produced by the internal checker, printed by the CLI and returned by the
+// fipscheck package, so fields added here reach every consumer.
+//
+// The JSON encoding is versioned by SchemaVersion. Fields are only added
+// within a major version; readers should ignore fields they do not know.
+package report
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// SchemaVersion is the version of the JSON encoding of Scan.
+const SchemaVersion = "1.6"
+
+// Scan is the result of scanning a filesystem tree for Go binaries.
+type Scan struct {
+	// SchemaVersion is the SchemaVersion the scan was encoded with
+	SchemaVersion string `json:"schemaVersion"`
+	// Root is the scanned path
+	Root     string         `json:"root"`
+	Binaries []BinaryReport `json:"binaries"`
+	// Diagnostics lists the parts of the tree that could not be scanned (since 1.1)
+	Diagnostics ScanDiagnostics `json:"diagnostics"`
+}
+
+// NewScan returns the scan result for the binaries found below root.
+func NewScan(root string, binaries []BinaryReport, diagnostics ScanDiagnostics) Scan {
+	if binaries == nil {
+		binaries = []BinaryReport{}
+	}
+	return Scan{SchemaVersion: SchemaVersion, Root: root, Binaries: binaries, Diagnostics: diagnostics}
+}
+
+// BinaryReport contains the FIPS compliance information for a binary file.
+type BinaryReport struct {
+	// RelativePath is the path of the binary relative to the scan root
+	RelativePath string `json:"path"`
+	// Type indicates the type of binary (e.g., "gobinary")
+	Type            string                `json:"type"`
+	GoBinaryDetails GoBinaryReportDetails `json:"goBinary"`
+	// Digest is the SHA-256 digest of the binary, e.g. "sha256:4f2a..." (since 1.2)
+	Digest string `json:"digest,omitempty"`
+	// AliasOf is the path of an identical binary, e.g. a hardlink or copy,
+	// whose analysis this report reuses. It is empty for the path the binary
+	// was analyzed at (since 1.2).
+	AliasOf string `json:"aliasOf,omitempty"`
+	// Aliases lists the other paths of identical binaries. It is only set by
+	// scans that return all reports at once, as streams cannot know later
+	// paths yet (since 1.2).
+	Aliases []string `json:"aliases,omitempty"`
+	// Cached indicates the analysis was taken from a cache of earlier scans (since 1.3)
+	Cached bool `json:"cached,omitempty"`
+	// SymlinkTarget is set if the path is a symlink. It is the path of the
+	// binary the symlink resolves to, relative to the scan root, and the
+	// symlink is an alias of it (since 1.4).
+	SymlinkTarget string `json:"symlinkTarget,omitempty"`
+	// Entrypoint indicates the binary is the one a container image runs by
+	// default, as set by its Entrypoint and Cmd (since 1.5).
+	Entrypoint bool `json:"entrypoint,omitempty"`
+	// Error contains any error that occurred while scanning this binary.
+	// It is encoded as its message.
+	Error error `json:"-"`
+}
+
+// MarshalJSON encodes the report with Error as a string.
+func (r BinaryReport) MarshalJSON() ([]byte, error) {
+	// The conversion drops the methods of BinaryReport, which would recurse
+	type plain BinaryReport
+	v := struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain: plain(r)}
+	if r.Error != nil {
+		v.Error = r.Error.Error()
+	}
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON decodes a report encoded by MarshalJSON. A decoded Error
+// only preserves the message of the original error.
+func (r *BinaryReport) UnmarshalJSON(data []byte) error {
+	type plain BinaryReport
+	var v struct {
+		plain
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*r = BinaryReport(v.plain)
+	if v.Error != "" {
+		r.Error = errors.New(v.Error)
+	}
+	return nil
+}
+
+// GoBinaryReportDetails contains detailed information about a Go binary's FIPS capabilities.
+type GoBinaryReportDetails struct {
+	GoVersion        string `json:"goVersion"`
+	Module           string `json:"module,omitempty"`
+	UseSystemcrypto  bool   `json:"useSystemcrypto"`
+	CGOEnabled       bool   `json:"cgoEnabled"`
+	FailsOnFIPSCheck bool   `json:"failsOnFIPSCheck"`          // Indicates if the binary fails when run with GOFIPS=1
+	RuntimePanicLog  string `json:"runtimePanicLog,omitempty"` // Captures the panic log from runtime FIPS check
+
+	RuntimeOutcome RuntimeOutcome `json:"runtimeOutcome"`          // Classification of the runtime FIPS check
+	PanicMessage   string         `json:"panicMessage,omitempty"`  // Panic message parsed from the runtime output, if any
+	ExitCode       int            `json:"exitCode"`                // Exit code of the runtime FIPS check, -1 if it did not exit normally
+	Signal         string         `json:"signal,omitempty"`        // Signal that terminated the binary, if any
+	RuntimeStdout  string         `json:"runtimeStdout,omitempty"` // Captures stdout from runtime FIPS check
+
+	Architecture        string `json:"architecture,omitempty"` // GOARCH name derived from the ELF header, empty if unknown
+	ForeignArchitecture bool   `json:"foreignArchitecture"`    // Indicates the binary cannot run natively on this host
+	StaticOnly          bool   `json:"staticOnly"`             // Indicates the verdict is based on static analysis only
+}
@@ -0,0 +1,90 @@
+package report
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestScanJSONRoundTrip(t *testing.T) {
+	scan := NewScan("/rootfs", []BinaryReport{
+		{
+			RelativePath: "usr/bin/app",
+			Type:         "gobinary",
+			GoBinaryDetails: GoBinaryReportDetails{
+				GoVersion:       "go1.24.4",
+				UseSystemcrypto: true,
+				CGOEnabled:      true,
+				RuntimeOutcome:  RuntimeOutcomeFIPSPanic,
+				ExitCode:        2,
+			},
+			Error: errors.New("runtime FIPS check failed"),
+		},
+	}, ScanDiagnostics{})
+
+	data, err := json.Marshal(scan)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	for _, want := range []string{`"schemaVersion":"` + SchemaVersion + `"`, `"path":"usr/bin/app"`, `"runtimeOutcome":"fips-panic"`, `"error":"runtime FIPS check failed"`} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("Expected %s in %s", want, data)
+		}
+	}
+
+	var decoded Scan
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	got := decoded.Binaries[0]
+	if decoded.SchemaVersion != SchemaVersion || decoded.Root != "/rootfs" {
+		t.Errorf("Unexpected scan %+v", decoded)
+	}
+	if got.GoBinaryDetails != scan.Binaries[0].GoBinaryDetails || got.RelativePath != "usr/bin/app" {
+		t.Errorf("Expected %+v, got %+v", scan.Binaries[0], got)
+	}
+	if got.Error == nil || got.Error.Error() != "runtime FIPS check failed" {
+		t.Errorf("Expected the error message to survive, got %v", got.Error)
+	}
+}
+
+func TestNewScanNoBinaries(t *testing.T) {
+	data, err := json.Marshal(NewScan("/", nil, ScanDiagnostics{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `"binaries":[]`) {
+		t.Errorf("Expected an empty list, got %s", data)
+	}
+}
+
+func TestRuntimeOutcomeUnknownName(t *testing.T) {
+	var o RuntimeOutcome
+	if err := json.Unmarshal([]byte(`"from-the-future"`), &o); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if o != RuntimeOutcomeUnknown {
+		t.Errorf("Expected %s, got %s", RuntimeOutcomeUnknown, o)
+	}
+}
+
+func TestExecError(t *testing.T) {
+	err := fmt.Errorf("runtime FIPS check inconclusive: %w", &ExecError{Path: "/bin/app", ExitCode: -1, Err: ErrForeignArch})
+	var execErr *ExecError
+	if !errors.As(err, &execErr) || execErr.Path != "/bin/app" {
+		t.Errorf("Expected an ExecError, got %v", err)
+	}
+	if !errors.Is(err, ErrForeignArch) {
+		t.Errorf("Expected ErrForeignArch, got %v", err)
+	}
+	if got := (&ExecError{Path: "/bin/app", ExitCode: 3}).Error(); got != "exec /bin/app: exit status 3" {
+		t.Errorf("Unexpected message %q", got)
+	}
+	if !errors.Is(&ExecError{Path: "/bin/app", Err: &fs.PathError{Op: "fork/exec", Path: "/bin/app", Err: syscall.EACCES}}, ErrPermission) {
+		t.Error("Expected EACCES to match ErrPermission")
+	}
+}
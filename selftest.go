@@ -0,0 +1,63 @@
+package fipscheck
+
+import (
+	"context"
+	"time"
+)
+
+// SelfTestResult is the outcome of a single cryptographic self-test.
+type SelfTestResult struct {
+	// Name identifies the algorithm, e.g. "AES-128-GCM"
+	Name string
+	// Approved is true for FIPS-approved algorithms, which must reproduce
+	// their known answers. Non-approved algorithms pass when they are rejected.
+	Approved bool
+	Passed   bool
+	Duration time.Duration
+	// Error explains why the test failed
+	Error error
+}
+
+// SelfTestReport holds the results of RunSelfTests.
+type SelfTestReport struct {
+	// Library is the libcrypto the tests ran against
+	Library string
+	// FIPSMode indicates the tests ran with FIPS mode enabled
+	FIPSMode bool
+	// FIPSModeError is set if FIPS mode could not be enabled
+	FIPSModeError error
+	// FIPSRestoreError is set if FIPS mode, enabled for the tests, could
+	// not be disabled again afterwards, leaving the process in FIPS mode
+	FIPSRestoreError error
+	Results          []SelfTestResult
+}
+
+// Passed reports whether every self-test passed in FIPS mode.
+func (r SelfTestReport) Passed() bool {
+	if !r.FIPSMode {
+		return false
+	}
+	for _, result := range r.Results {
+		if !result.Passed {
+			return false
+		}
+	}
+	return true
+}
+
+// RunSelfTests runs known-answer tests for SHA-2, HMAC, AES-GCM and HKDF and
+// pairwise consistency tests for ECDSA P-256/P-384 and RSA-PSS through the
+// host's OpenSSL, and checks that MD5, RC4 and ChaCha20-Poly1305 are rejected.
+//
+// The tests run in FIPS mode: if OpenSSL is not already in FIPS mode, it is
+// enabled for the duration of the tests. Like HostProber.Reprobe, RunSelfTests
+// changes process-wide OpenSSL state and must not run concurrently with other
+// OpenSSL use. OpenSSL is loaded as by CheckHostFIPS if needed.
+//
+// In builds without cgo, RunSelfTests returns ErrHostCheckUnavailable.
+func RunSelfTests(ctx context.Context) (SelfTestReport, error) {
+	if err := defaultHostProber.Init(ctx); err != nil {
+		return SelfTestReport{}, err
+	}
+	return runSelfTests(ctx)
+}
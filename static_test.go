@@ -0,0 +1,493 @@
+package fipscheck
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/bahe-msft/fips-check/internal/testutil"
+	"github.com/bahe-msft/fips-check/report"
+)
+
+func TestCheckBinariesStatic(t *testing.T) {
+	// Copy the test binary, which is a Go binary, into an otherwise empty tree
+	tempDir := t.TempDir()
+	testutil.WriteTestBinaries(t, tempDir, "app")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reports, err := CheckBinariesStatic(ctx, tempDir)
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+
+	details := reports[0].GoBinaryDetails
+	if details.GoVersion == "" {
+		t.Error("Expected Go version from static analysis")
+	}
+	if !details.StaticOnly {
+		t.Error("Expected static-only verdict")
+	}
+	if details.RuntimeOutcome != RuntimeOutcomeSkipped {
+		t.Errorf("Expected runtime outcome %s, got %s", RuntimeOutcomeSkipped, details.RuntimeOutcome)
+	}
+	if details.RuntimePanicLog != "" || details.RuntimeStdout != "" {
+		t.Error("Expected no runtime output in static mode")
+	}
+}
+
+func TestHostCheckSupported(t *testing.T) {
+	hostInfo := CheckHostFIPS()
+	if HostCheckSupported {
+		if errors.Is(hostInfo.Error, ErrHostCheckUnavailable) {
+			t.Error("Host check is supported but reported as unavailable")
+		}
+		return
+	}
+	if !errors.Is(hostInfo.Error, ErrHostCheckUnavailable) {
+		t.Errorf("Expected ErrHostCheckUnavailable without cgo, got %v", hostInfo.Error)
+	}
+	if hostInfo.FIPSCapable {
+		t.Error("Expected host not to be reported as FIPS capable without cgo")
+	}
+
+	var prober HostProber
+	if err := prober.Init(context.Background()); !errors.Is(err, ErrHostCheckUnavailable) {
+		t.Errorf("Expected HostProber.Init to fail with ErrHostCheckUnavailable, got %v", err)
+	}
+}
+
+func TestHostProberRoot(t *testing.T) {
+	root := t.TempDir()
+	files := map[string]string{
+		"proc/sys/crypto/fips_enabled": "1\n",
+		"proc/cmdline":                 "BOOT_IMAGE=/vmlinuz ro fips=1 quiet\n",
+		"etc/crypto-policies/config":   "# system-wide policy\nFIPS:OSPP\n",
+	}
+	testutil.WriteFiles(t, root, files)
+
+	prober := HostProber{Root: root}
+	_ = prober.Init(context.Background())
+	info := prober.Info()
+
+	if info.HostRoot != root {
+		t.Errorf("Expected host root %s, got %s", root, info.HostRoot)
+	}
+	if !info.KernelFIPSEnabled.Enabled || info.KernelFIPSEnabled.Source != "/proc/sys/crypto/fips_enabled" {
+		t.Errorf("Unexpected kernel FIPS signal %+v", info.KernelFIPSEnabled)
+	}
+	if !info.KernelCmdlineFIPS.Enabled || info.KernelCmdlineFIPS.Value != "1" {
+		t.Errorf("Unexpected kernel cmdline signal %+v", info.KernelCmdlineFIPS)
+	}
+	if info.SystemFIPSMarker.Found || info.SystemFIPSMarker.Enabled {
+		t.Errorf("Expected no /etc/system-fips marker, got %+v", info.SystemFIPSMarker)
+	}
+	if !info.CryptoPolicy.Enabled || info.CryptoPolicy.Value != "FIPS:OSPP" {
+		t.Errorf("Unexpected crypto policy signal %+v", info.CryptoPolicy)
+	}
+}
+
+func TestHostProberInvalidCMVPCatalog(t *testing.T) {
+	prober := HostProber{CMVPCatalog: filepath.Join(t.TempDir(), "missing.json")}
+	err := prober.Init(context.Background())
+	if !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected a missing catalog to fail Init, got %v", err)
+	}
+}
+
+func TestScanBinaries(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.WriteTestBinaries(t, tempDir, "a", "b", "c")
+	if err := os.WriteFile(filepath.Join(tempDir, "notes.txt"), []byte("not a binary"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	var last ScanProgress
+	seen := map[string]bool{}
+	for report, err := range ScanBinaries(ctx, tempDir, WithRuntimeProbe(false), WithProgress(func(p ScanProgress) { last = p })) {
+		if err != nil {
+			t.Fatalf("ScanBinaries failed: %v", err)
+		}
+		if !report.GoBinaryDetails.StaticOnly {
+			t.Errorf("Expected static-only report for %s", report.RelativePath)
+		}
+		seen[report.RelativePath] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("Expected 3 reports, got %v", seen)
+	}
+	if !last.WalkDone || last.FilesWalked != 4 || last.Candidates != 3 || last.Completed != 3 || last.ProbesRunning != 0 {
+		t.Errorf("Unexpected final progress %+v", last)
+	}
+
+	// Breaking out of the loop stops the scan without an error
+	count := 0
+	for _, err := range ScanBinaries(ctx, tempDir, WithRuntimeProbe(false)) {
+		if err != nil {
+			t.Fatalf("ScanBinaries failed: %v", err)
+		}
+		count++
+		break
+	}
+	if count != 1 {
+		t.Errorf("Expected to stop after 1 report, got %d", count)
+	}
+}
+
+func TestScanBinariesCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	var lastErr error
+	for _, err := range ScanBinaries(ctx, t.TempDir(), WithRuntimeProbe(false)) {
+		lastErr = err
+	}
+	if !errors.Is(lastErr, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", lastErr)
+	}
+}
+
+func TestAnalyzeFile(t *testing.T) {
+	app := filepath.Join(t.TempDir(), "app")
+	testutil.WriteTestBinaries(t, filepath.Dir(app), "app")
+
+	report, err := AnalyzeFile(context.Background(), app)
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if report.RelativePath != app || report.Type != "gobinary" {
+		t.Errorf("Unexpected report %+v", report)
+	}
+	details := report.GoBinaryDetails
+	if details.GoVersion == "" || !details.StaticOnly || details.RuntimeOutcome != RuntimeOutcomeSkipped {
+		t.Errorf("Expected a static-only report with a Go version, got %+v", details)
+	}
+
+	notGo := filepath.Join(t.TempDir(), "script.sh")
+	if err := os.WriteFile(notGo, []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := AnalyzeFile(context.Background(), notGo); !errors.Is(err, ErrNotELF) {
+		t.Errorf("Expected ErrNotELF for a script, got %v", err)
+	}
+
+	// An ELF binary that is not a Go binary carries no build info
+	for _, name := range []string{"/bin/true", "/usr/bin/true"} {
+		if data, err := os.ReadFile(name); err == nil && bytes.HasPrefix(data, []byte("\x7fELF")) {
+			if _, err := AnalyzeFile(context.Background(), name); !errors.Is(err, ErrNoBuildInfo) {
+				t.Errorf("Expected ErrNoBuildInfo for %s, got %v", name, err)
+			}
+			break
+		}
+	}
+
+	if _, err := AnalyzeFile(context.Background(), filepath.Join(t.TempDir(), "missing")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Expected os.ErrNotExist for a missing file, got %v", err)
+	}
+}
+
+func TestAnalyzeReader(t *testing.T) {
+	dir := t.TempDir()
+	data := testutil.WriteTestBinaries(t, dir, "app")
+
+	report, err := AnalyzeReader(context.Background(), bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatalf("AnalyzeReader failed: %v", err)
+	}
+	fromFile, err := AnalyzeFile(context.Background(), filepath.Join(dir, "app"))
+	if err != nil {
+		t.Fatalf("AnalyzeFile failed: %v", err)
+	}
+	if report.RelativePath != "" {
+		t.Errorf("Expected no path for a reader, got %q", report.RelativePath)
+	}
+	if report.GoBinaryDetails != fromFile.GoBinaryDetails {
+		t.Errorf("Expected the same details as AnalyzeFile, got %+v and %+v", report.GoBinaryDetails, fromFile.GoBinaryDetails)
+	}
+
+	if _, err := AnalyzeReader(context.Background(), bytes.NewReader(data), 64); err == nil {
+		t.Error("Expected an error for a truncated binary")
+	}
+}
+
+func TestCheckBinariesFS(t *testing.T) {
+	// The files live in memory, so nothing can be executed
+	data := testutil.TestBinary(t)
+	fsys := fstest.MapFS{
+		"usr/bin/app":     {Data: data, Mode: 0755},
+		"usr/lib/app.dat": {Data: data, Mode: 0644},
+		"etc/motd":        {Data: []byte("hello"), Mode: 0644},
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	reports, err := CheckBinariesFS(ctx, fsys)
+	if err != nil {
+		t.Fatalf("CheckBinariesFS failed: %v", err)
+	}
+	if len(reports) != 1 {
+		t.Fatalf("Expected 1 report, got %d", len(reports))
+	}
+	if reports[0].RelativePath != filepath.FromSlash("usr/bin/app") {
+		t.Errorf("Expected usr/bin/app, got %s", reports[0].RelativePath)
+	}
+	details := reports[0].GoBinaryDetails
+	if details.GoVersion == "" || !details.StaticOnly || details.RuntimeOutcome != RuntimeOutcomeSkipped {
+		t.Errorf("Expected a static-only report with a Go version, got %+v", details)
+	}
+
+	count := 0
+	for report, err := range ScanBinariesFS(ctx, fsys) {
+		if err != nil {
+			t.Fatalf("ScanBinariesFS failed: %v", err)
+		}
+		if !report.GoBinaryDetails.StaticOnly {
+			t.Errorf("Expected static-only report for %s", report.RelativePath)
+		}
+		count++
+	}
+	if count != 1 {
+		t.Errorf("Expected 1 report, got %d", count)
+	}
+}
+
+func TestCheckBinariesOptions(t *testing.T) {
+	tempDir := t.TempDir()
+	data := testutil.WriteTestBinaries(t, tempDir, "usr/bin/app", "usr/bin/tool", "opt/app/server")
+	if err := os.Symlink("../../opt/app/server", filepath.Join(tempDir, "usr/bin/server")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want []string
+	}{
+		{"default", nil, []string{"opt/app/server", "usr/bin/app", "usr/bin/server", "usr/bin/tool"}},
+		{"include base name", []Option{WithInclude("app", "tool")}, []string{"usr/bin/app", "usr/bin/tool"}},
+		{"include path", []Option{WithInclude("usr/bin/*")}, []string{"usr/bin/app", "usr/bin/server", "usr/bin/tool"}},
+		{"exclude directory", []Option{WithExclude("opt")}, []string{"usr/bin/app", "usr/bin/server", "usr/bin/tool"}},
+		{"exclude wins", []Option{WithInclude("usr/bin/*"), WithExclude("t*")}, []string{"usr/bin/app", "usr/bin/server"}},
+		{"skip symlinks", []Option{WithSymlinkPolicy(SymlinkSkip)}, []string{"opt/app/server", "usr/bin/app", "usr/bin/tool"}},
+		{"max file size", []Option{WithMaxFileSize(int64(len(data)) - 1)}, nil},
+		{"concurrency", []Option{WithConcurrency(1), WithAnalyzers(AnalyzerGoBinary)}, []string{"opt/app/server", "usr/bin/app", "usr/bin/server", "usr/bin/tool"}},
+		{"analysis concurrency", []Option{WithAnalysisConcurrency(1), WithConcurrency(1)}, []string{"opt/app/server", "usr/bin/app", "usr/bin/server", "usr/bin/tool"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reports, err := CheckBinariesStatic(ctx, tempDir, tt.opts...)
+			if err != nil {
+				t.Fatalf("CheckBinariesStatic failed: %v", err)
+			}
+			var got []string
+			for _, report := range reports {
+				got = append(got, filepath.ToSlash(report.RelativePath))
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+
+	for _, opt := range []Option{WithConcurrency(-1), WithAnalysisConcurrency(-1), WithExclude("["), WithAnalyzers("elf")} {
+		if _, err := CheckBinariesStatic(ctx, tempDir, opt); err == nil {
+			t.Error("Expected invalid options to fail the scan")
+		}
+	}
+}
+
+// unreadableFS fails to read the directory named locked.
+type unreadableFS struct {
+	fstest.MapFS
+	locked string
+}
+
+func (f unreadableFS) Open(name string) (fs.File, error) {
+	if name == f.locked {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.Open(name)
+}
+
+func (f unreadableFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	if name == f.locked {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrPermission}
+	}
+	return f.MapFS.ReadDir(name)
+}
+
+func TestScanDiagnostics(t *testing.T) {
+	fsys := unreadableFS{
+		MapFS: fstest.MapFS{
+			"usr/bin/script":   {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+			"usr/local/bin/x":  {Data: []byte("#!/bin/sh\n"), Mode: 0755},
+			"usr/local/README": {Data: []byte("hello"), Mode: 0644},
+		},
+		locked: "usr/local",
+	}
+	ctx := context.Background()
+
+	scan, err := ScanFS(ctx, fsys)
+	if err != nil {
+		t.Fatalf("ScanFS failed: %v", err)
+	}
+	if scan.Diagnostics.Complete() || scan.Diagnostics.Count != 1 {
+		t.Fatalf("Expected 1 diagnostic, got %+v", scan.Diagnostics)
+	}
+	if e := scan.Diagnostics.Errors[0]; e.Path != filepath.FromSlash("usr/local") || !errors.Is(e, ErrPermission) {
+		t.Errorf("Unexpected diagnostic %v", e)
+	}
+
+	scan, err = ScanFS(ctx, fsys, WithStrict(true))
+	if !errors.Is(err, report.ErrIncompleteScan) {
+		t.Errorf("Expected ErrIncompleteScan in strict mode, got %v", err)
+	}
+	if scan.Diagnostics.Count != 1 {
+		t.Errorf("Expected the scan along with the error, got %+v", scan)
+	}
+
+	var lastErr error
+	for _, err := range ScanBinariesFS(ctx, fsys, WithStrict(true)) {
+		lastErr = err
+	}
+	if !errors.Is(lastErr, report.ErrIncompleteScan) {
+		t.Errorf("Expected the stream to end with ErrIncompleteScan, got %v", lastErr)
+	}
+
+	complete, err := ScanFS(ctx, fsys.MapFS, WithStrict(true))
+	if err != nil || !complete.Diagnostics.Complete() {
+		t.Errorf("Expected a complete scan, got %+v, %v", complete.Diagnostics, err)
+	}
+}
+
+func TestCheckBinariesDeduplicates(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.WriteTestBinaries(t, tempDir, "a", "b")
+	if err := os.Link(filepath.Join(tempDir, "a"), filepath.Join(tempDir, "c")); err != nil {
+		t.Fatal(err)
+	}
+
+	reports, err := CheckBinariesStatic(context.Background(), tempDir)
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("Expected a report for each of the 3 paths, got %d", len(reports))
+	}
+	analyzed := 0
+	for _, report := range reports {
+		if report.Digest == "" || report.Digest != reports[0].Digest {
+			t.Errorf("Expected the same digest for %s, got %q", report.RelativePath, report.Digest)
+		}
+		if report.AliasOf == "" {
+			analyzed++
+		}
+		if len(report.Aliases) != 2 || slices.Contains(report.Aliases, report.RelativePath) {
+			t.Errorf("Expected the 2 other paths as aliases of %s, got %v", report.RelativePath, report.Aliases)
+		}
+		if report.GoBinaryDetails != reports[0].GoBinaryDetails {
+			t.Errorf("Expected the shared analysis for %s", report.RelativePath)
+		}
+	}
+	if analyzed != 1 {
+		t.Errorf("Expected the binary to be analyzed once, got %d", analyzed)
+	}
+}
+
+func TestCheckBinariesSymlinks(t *testing.T) {
+	root := t.TempDir()
+	testutil.WriteTestBinaries(t, root, "opt/app/foo")
+	if err := os.MkdirAll(filepath.Join(root, "usr/bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	host := t.TempDir()
+	testutil.WriteTestBinaries(t, host, "host")
+	links := map[string]string{
+		// Absolute targets resolve inside the scan root, as in a mounted rootfs
+		"usr/bin/foo": "/opt/app/foo",
+		"usr/bin/bar": "../../../../opt/app/foo",
+		// The binary exists on the host, but not inside the scan root
+		"usr/bin/host": filepath.Join(host, "host"),
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	reports, err := CheckBinariesStatic(context.Background(), root)
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	var got []string
+	for _, report := range reports {
+		got = append(got, filepath.ToSlash(report.RelativePath))
+		if report.RelativePath == filepath.FromSlash("opt/app/foo") {
+			if report.AliasOf != "" || report.SymlinkTarget != "" {
+				t.Errorf("Expected the target to be analyzed itself, got %+v", report)
+			}
+			continue
+		}
+		if filepath.ToSlash(report.SymlinkTarget) != "opt/app/foo" || filepath.ToSlash(report.AliasOf) != "opt/app/foo" {
+			t.Errorf("Expected %s to be an alias of its target, got target %q, alias of %q",
+				report.RelativePath, report.SymlinkTarget, report.AliasOf)
+		}
+	}
+	want := []string{"opt/app/foo", "usr/bin/bar", "usr/bin/foo"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected %v, got %v", want, got)
+	}
+
+	reports, err = CheckBinariesStatic(context.Background(), root, WithSymlinkPolicy(SymlinkSkip))
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	if len(reports) != 1 || reports[0].SymlinkTarget != "" {
+		t.Errorf("Expected only the target with SymlinkSkip, got %+v", reports)
+	}
+}
+
+func TestCheckBinariesCache(t *testing.T) {
+	tempDir := t.TempDir()
+	testutil.WriteTestBinaries(t, tempDir, "app")
+	cacheDir := t.TempDir()
+
+	first, err := CheckBinariesStatic(context.Background(), tempDir, WithCache(cacheDir))
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	second, err := CheckBinariesStatic(context.Background(), tempDir, WithCache(cacheDir))
+	if err != nil {
+		t.Fatalf("CheckBinariesStatic failed: %v", err)
+	}
+	if len(first) != 1 || len(second) != 1 {
+		t.Fatalf("Expected one report per scan, got %d and %d", len(first), len(second))
+	}
+	if first[0].Cached {
+		t.Error("Expected the first scan to analyze the binary")
+	}
+	if !second[0].Cached {
+		t.Error("Expected the second scan to use the cache")
+	}
+	if first[0].GoBinaryDetails != second[0].GoBinaryDetails {
+		t.Errorf("Expected the cached details %+v to match %+v", second[0].GoBinaryDetails, first[0].GoBinaryDetails)
+	}
+}